@@ -1,6 +1,10 @@
 package document
 
-import "github.com/benbjohnson/immutable"
+import (
+	"sync/atomic"
+
+	"github.com/benbjohnson/immutable"
+)
 
 type ParagraphIterator interface {
 	Done() bool
@@ -8,27 +12,66 @@ type ParagraphIterator interface {
 }
 
 type Document struct {
-	paragraphs *immutable.List[*Paragraph]
-	pageWidth  int
+	paragraphs  *immutable.List[*Paragraph]
+	pageSetup   PageSetup
+	hyphenation HyphenationSettings
+
+	revision uint64
+	edit     Edit
+}
+
+// Edit describes how the paragraphs of a document differ from those of the document it was
+// derived from. The paragraphs with indices in [Start, End) of the parent were replaced by Inserted
+// paragraphs. An edit which does not change any paragraphs has Start == End and Inserted == 0.
+type Edit struct {
+	// Parent is the revision of the document this one was derived from or zero if it is unknown.
+	Parent uint64
+
+	Start, End int
+	Inserted   int
 }
 
+// lastRevision is the most recently allocated document revision.
+var lastRevision uint64
+
 func NewDocument() *Document {
 	return &Document{
 		paragraphs: immutable.NewList[*Paragraph](),
-		pageWidth:  80,
+		pageSetup:  DefaultPageSetup(),
+		revision:   atomic.AddUint64(&lastRevision, 1),
 	}
 }
 
-func (d *Document) setParagraph(i int, p *Paragraph) *Document {
+// derive returns a copy of the document with a new revision recording that the paragraphs
+// [start, end) were replaced by inserted paragraphs.
+func (d *Document) derive(start, end, inserted int) *Document {
 	nd := *d
-	nd.paragraphs = nd.paragraphs.Set(i, p)
+	nd.revision = atomic.AddUint64(&lastRevision, 1)
+	nd.edit = Edit{Parent: d.revision, Start: start, End: end, Inserted: inserted}
 	return &nd
 }
 
+// Revision returns a number which uniquely identifies this version of the document.
+func (d *Document) Revision() uint64 {
+	return d.revision
+}
+
+// LastEdit returns the edit which derived this document from its parent.
+func (d *Document) LastEdit() Edit {
+	return d.edit
+}
+
+func (d *Document) setParagraph(i int, p *Paragraph) *Document {
+	nd := d.derive(i, i+1, 1)
+	nd.paragraphs = nd.paragraphs.Set(i, p)
+	return nd
+}
+
 func (d *Document) appendParagraph(p *Paragraph) *Document {
-	nd := *d
+	n := d.paragraphs.Len()
+	nd := d.derive(n, n, 1)
 	nd.paragraphs = nd.paragraphs.Append(p)
-	return &nd
+	return nd
 }
 
 func (d *Document) replaceParagraphs(start int, end int, ps []*Paragraph) *Document {
@@ -55,15 +98,15 @@ func (d *Document) replaceParagraphs(start int, end int, ps []*Paragraph) *Docum
 		}
 	}
 
-	nd := *d
+	nd := d.derive(start, end, len(ps))
 	nd.paragraphs = lb.List()
-	return &nd
+	return nd
 }
 
 func (d *Document) setParas(ps *immutable.List[*Paragraph]) *Document {
-	nd := *d
+	nd := d.derive(0, d.paragraphs.Len(), ps.Len())
 	nd.paragraphs = ps
-	return &nd
+	return nd
 }
 
 func (d *Document) StartPoint() *Point {
@@ -74,6 +117,24 @@ func (d *Document) EndPoint() *Point {
 	return &Point{d: d, paraIndex: d.paragraphs.Len()}
 }
 
+// PointAt returns the point at a byte offset within the paragraph at index paraIndex. Indices and
+// offsets outside the document are clamped to the nearest valid point.
+func (d *Document) PointAt(paraIndex, textOffset int) *Point {
+	if paraIndex >= d.paragraphs.Len() {
+		return d.EndPoint()
+	}
+	if paraIndex < 0 {
+		return d.StartPoint()
+	}
+	if textOffset < 0 {
+		textOffset = 0
+	}
+	if n := d.paragraphs.Get(paraIndex).TextLength(); textOffset > n {
+		textOffset = n
+	}
+	return &Point{d: d, paraIndex: paraIndex, textOffset: textOffset}
+}
+
 func (d *Document) ParagraphSlice(start int, end int) ParagraphIterator {
 	return d.paragraphs.Slice(start, end).Iterator()
 }
@@ -89,3 +150,23 @@ func (d *Document) GetParagraph(index int) *Paragraph {
 func (d *Document) ParagraphCount() int {
 	return d.paragraphs.Len()
 }
+
+func (d *Document) PageSetup() PageSetup {
+	return d.pageSetup
+}
+
+func (d *Document) SetPageSetup(ps PageSetup) *Document {
+	nd := d.derive(0, 0, 0)
+	nd.pageSetup = ps
+	return nd
+}
+
+func (d *Document) Hyphenation() HyphenationSettings {
+	return d.hyphenation
+}
+
+func (d *Document) SetHyphenation(hs HyphenationSettings) *Document {
+	nd := d.derive(0, 0, 0)
+	nd.hyphenation = hs
+	return nd
+}
@@ -0,0 +1,98 @@
+package document
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// WithDotCommand returns the page setup changed by a single WordStar dot command line such as
+// ".he Page #" or ".pl 72". Commands are case insensitive. Running text commands (.he, .oh, .eh,
+// .fo, .of and .ef) take the rest of the line after a single space as their text; .he and .fo set
+// both odd and even pages. The other commands take a number. Since a later command may make room
+// for an earlier one, the page setup as a whole is not checked. See Check.
+func (ps PageSetup) WithDotCommand(line string) (PageSetup, error) {
+	line = strings.TrimRight(line, "\r\n")
+	if len(line) < 3 || line[0] != '.' {
+		return ps, fmt.Errorf("document: not a dot command: %q", line)
+	}
+	command, arg := strings.ToLower(line[1:3]), line[3:]
+
+	// Running text keeps its leading spaces bar the one separating it from the command.
+	if strings.HasPrefix(arg, " ") {
+		arg = arg[1:]
+	}
+	switch command {
+	case "he":
+		ps.Header = NewRunningText(arg)
+		return ps, nil
+	case "oh":
+		ps.Header.Odd = arg
+		return ps, nil
+	case "eh":
+		ps.Header.Even = arg
+		return ps, nil
+	case "fo":
+		ps.Footer = NewRunningText(arg)
+		return ps, nil
+	case "of":
+		ps.Footer.Odd = arg
+		return ps, nil
+	case "ef":
+		ps.Footer.Even = arg
+		return ps, nil
+	}
+
+	var field *int
+	switch command {
+	case "pl":
+		field = &ps.Length
+	case "mt":
+		field = &ps.TopMargin
+	case "mb":
+		field = &ps.BottomMargin
+	case "hm":
+		field = &ps.HeaderMargin
+	case "fm":
+		field = &ps.FooterMargin
+	case "po":
+		field = &ps.PageOffset
+	case "pn":
+		field = &ps.FirstPageNumber
+	default:
+		return ps, fmt.Errorf("document: unknown dot command %q", line[:3])
+	}
+
+	n, err := strconv.Atoi(strings.TrimSpace(arg))
+	if err != nil || n < 0 {
+		return ps, fmt.Errorf("document: bad number in dot command %q", line)
+	}
+	*field = n
+	return ps, nil
+}
+
+// ReadDotCommands applies the dot commands read from r, one per line, to ps. Blank lines and
+// comment lines starting with ".." are ignored. An error is returned if the resulting page setup
+// cannot be printed.
+func (ps PageSetup) ReadDotCommands(r io.Reader) (PageSetup, error) {
+	scanner := bufio.NewScanner(r)
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		line := scanner.Text()
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "..") {
+			continue
+		}
+
+		var err error
+		if ps, err = ps.WithDotCommand(line); err != nil {
+			return ps, fmt.Errorf("line %d: %w", lineNum, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return ps, err
+	}
+	return ps, ps.Check()
+}
@@ -0,0 +1,188 @@
+package document
+
+// Alignment gives how the lines of a paragraph are positioned horizontally.
+type Alignment int
+
+const (
+	AlignLeft Alignment = iota
+	AlignRight
+	AlignCentre
+	AlignJustify
+)
+
+// LineSpacing gives the spacing between the lines of a paragraph, as set by WordStar's ".ls" dot
+// command.
+type LineSpacing int
+
+const (
+	LineSpacingSingle LineSpacing = iota
+	LineSpacingOneAndAHalf
+	LineSpacingDouble
+)
+
+// TabKind gives how text following a tab is aligned to the tab stop.
+type TabKind int
+
+const (
+	// TabLeft starts the text at the stop.
+	TabLeft TabKind = iota
+
+	// TabRight ends the text at the stop.
+	TabRight
+
+	// TabCentre centres the text on the stop.
+	TabCentre
+
+	// TabDecimal aligns the first '.' in the text with the stop.
+	TabDecimal
+)
+
+// DefaultTabInterval is the spacing of tab stops after the last custom tab stop.
+const DefaultTabInterval = 8
+
+// TabStop is a position to which a tab character advances.
+type TabStop struct {
+	// Column is the column of the stop counting from zero at the left edge of the page or screen.
+	Column int
+
+	Kind TabKind
+}
+
+// ParagraphFormat describes how a paragraph is laid out.
+type ParagraphFormat struct {
+	Alignment Alignment
+
+	// LeftMargin is the column at which lines start.
+	LeftMargin int
+
+	// RightMargin is the column after the last column in which text may be set. If it is zero,
+	// or beyond the available width, lines may use the full width.
+	RightMargin int
+
+	// LeftIndent and RightIndent move the edges of every line in from the margins.
+	LeftIndent  int
+	RightIndent int
+
+	// FirstLineIndent is added to the left indent for the first line of the paragraph. A negative
+	// value gives a hanging indent.
+	FirstLineIndent int
+
+	LineSpacing LineSpacing
+
+	// SpaceBefore and SpaceAfter are the number of blank lines before and after the paragraph.
+	SpaceBefore int
+	SpaceAfter  int
+
+	// TabStops are custom tab stops in increasing order of column. The slice must not be modified
+	// once passed to SetParagraphFormat.
+	TabStops []TabStop
+}
+
+// NextTabStop returns the first tab stop after column x. Left stops at every multiple of
+// DefaultTabInterval are used after the last custom tab stop.
+func (f ParagraphFormat) NextTabStop(x int) TabStop {
+	for _, ts := range f.TabStops {
+		if ts.Column > x {
+			return ts
+		}
+	}
+	return TabStop{Column: (x/DefaultTabInterval + 1) * DefaultTabInterval}
+}
+
+// TabStopsBefore returns all tab stops, custom and default, before column limit.
+func (f ParagraphFormat) TabStopsBefore(limit int) []TabStop {
+	var stops []TabStop
+	for ts := f.NextTabStop(-1); ts.Column < limit; ts = f.NextTabStop(ts.Column) {
+		stops = append(stops, ts)
+	}
+	return stops
+}
+
+// BlankLinesAfter returns the number of blank lines which follow line n, counting from zero, of a
+// paragraph set with spacing s. With one and a half spacing, every other line is followed by a
+// blank line.
+func (s LineSpacing) BlankLinesAfter(n int) int {
+	switch s {
+	case LineSpacingOneAndAHalf:
+		return n % 2
+	case LineSpacingDouble:
+		return 1
+	}
+	return 0
+}
+
+// Margins returns the first column and the column after the last column which text may occupy
+// when the available width is width. There is always room for at least one cell.
+func (f ParagraphFormat) Margins(width int) (left, right int) {
+	left, right = f.LeftMargin, f.RightMargin
+	if right <= 0 || right > width {
+		right = width
+	}
+	if left < 0 {
+		left = 0
+	}
+	if left >= right {
+		left = right - 1
+	}
+	if left < 0 {
+		left, right = 0, 1
+	}
+	return left, right
+}
+
+// LineColumns returns the first column and the column after the last column which a line of text
+// may occupy when the available width is width. The first line may differ from the others if there
+// is a first line indent. There is always room for at least one cell.
+func (f ParagraphFormat) LineColumns(width int, first bool) (start, end int) {
+	left, right := f.Margins(width)
+	start, end = left+f.LeftIndent, right-f.RightIndent
+	if first {
+		start += f.FirstLineIndent
+	}
+	if end > right {
+		end = right
+	}
+	if end < 1 {
+		end = 1
+	}
+	if start < 0 {
+		start = 0
+	}
+	if start >= end {
+		start = end - 1
+	}
+	return start, end
+}
+
+// WithTabStop returns a copy of the format with a custom tab stop added. Any existing custom tab
+// stop at the same column is replaced.
+func (f ParagraphFormat) WithTabStop(ts TabStop) ParagraphFormat {
+	stops := make([]TabStop, 0, len(f.TabStops)+1)
+	added := false
+	for _, s := range f.TabStops {
+		if !added && s.Column >= ts.Column {
+			stops = append(stops, ts)
+			added = true
+		}
+		if s.Column != ts.Column {
+			stops = append(stops, s)
+		}
+	}
+	if !added {
+		stops = append(stops, ts)
+	}
+	f.TabStops = stops
+	return f
+}
+
+// WithoutTabStop returns a copy of the format with any custom tab stop at column removed.
+func (f ParagraphFormat) WithoutTabStop(column int) ParagraphFormat {
+	stops := make([]TabStop, 0, len(f.TabStops))
+	for _, s := range f.TabStops {
+		if s.Column != column {
+			stops = append(stops, s)
+		}
+	}
+	f.TabStops = stops
+	return f
+}
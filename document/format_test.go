@@ -0,0 +1,58 @@
+package document
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMargins(t *testing.T) {
+	for _, c := range []struct {
+		f           ParagraphFormat
+		width       int
+		left, right int
+	}{
+		{ParagraphFormat{}, 80, 0, 80},
+		{ParagraphFormat{LeftMargin: 5, RightMargin: 65}, 80, 5, 65},
+		{ParagraphFormat{LeftMargin: 5, RightMargin: 65}, 40, 5, 40},
+		{ParagraphFormat{LeftMargin: 50}, 40, 39, 40},
+	} {
+		left, right := c.f.Margins(c.width)
+		if left != c.left || right != c.right {
+			t.Errorf("%+v.Margins(%v) = %v, %v; want %v, %v", c.f, c.width, left, right, c.left, c.right)
+		}
+	}
+}
+
+func TestLineColumns(t *testing.T) {
+	f := ParagraphFormat{LeftMargin: 5, RightMargin: 65, LeftIndent: 4, RightIndent: 2, FirstLineIndent: -4}
+
+	if start, end := f.LineColumns(80, true); start != 5 || end != 63 {
+		t.Errorf("First line: %v, %v", start, end)
+	}
+	if start, end := f.LineColumns(80, false); start != 9 || end != 63 {
+		t.Errorf("Other lines: %v, %v", start, end)
+	}
+
+	f.FirstLineIndent = -20
+	if start, _ := f.LineColumns(80, true); start != 0 {
+		t.Errorf("Hanging indent beyond page edge: %v", start)
+	}
+}
+
+func TestWithTabStop(t *testing.T) {
+	var f ParagraphFormat
+	f = f.WithTabStop(TabStop{Column: 10})
+	f = f.WithTabStop(TabStop{Column: 4})
+	f = f.WithTabStop(TabStop{Column: 10, Kind: TabRight})
+
+	want := []TabStop{{Column: 4}, {Column: 10, Kind: TabRight}}
+	if !reflect.DeepEqual(f.TabStops, want) {
+		t.Errorf("TabStops = %v; want %v", f.TabStops, want)
+	}
+
+	f = f.WithoutTabStop(4)
+	want = []TabStop{{Column: 10, Kind: TabRight}}
+	if !reflect.DeepEqual(f.TabStops, want) {
+		t.Errorf("TabStops = %v; want %v", f.TabStops, want)
+	}
+}
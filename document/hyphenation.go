@@ -0,0 +1,26 @@
+package document
+
+// HyphenationSettings control automatic hyphenation of the document.
+type HyphenationSettings struct {
+	// Language selects the hyphenation patterns, for example "en-gb". The document is not
+	// hyphenated automatically if the language is empty.
+	Language string
+
+	// Exceptions are words with their permitted hyphenation points marked by '-', for example
+	// "ta-ble". They take precedence over the patterns for the language. The slice must not be
+	// modified once passed to SetHyphenation.
+	Exceptions []string
+}
+
+// Equal returns true if both settings have the same language and exceptions.
+func (hs HyphenationSettings) Equal(other HyphenationSettings) bool {
+	if hs.Language != other.Language || len(hs.Exceptions) != len(other.Exceptions) {
+		return false
+	}
+	for i := range hs.Exceptions {
+		if hs.Exceptions[i] != other.Exceptions[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,144 @@
+package document
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// PageSetup describes how the document is divided into printed pages. All measurements are in
+// character cells (columns) or lines. The defaults follow WordStar's dot commands of the same
+// name.
+type PageSetup struct {
+	// Width is the width of the text area in columns.
+	Width int
+
+	// Length is the total number of lines on a page, including margins (.pl).
+	Length int
+
+	// TopMargin is the number of lines above the body text, including the header (.mt).
+	TopMargin int
+
+	// BottomMargin is the number of lines below the body text, including the footer (.mb).
+	BottomMargin int
+
+	// HeaderMargin is the number of lines between the header and the body text (.hm).
+	HeaderMargin int
+
+	// FooterMargin is the number of lines between the body text and the footer (.fm).
+	FooterMargin int
+
+	// PageOffset is the number of blank columns to the left of the text area when printed (.po).
+	PageOffset int
+
+	// FirstPageNumber is the number printed on the first page (.pn).
+	FirstPageNumber int
+
+	// Header is printed in the top margin of each page (.he, .oh, .eh).
+	Header RunningText
+
+	// Footer is printed in the bottom margin of each page (.fo, .of, .ef).
+	Footer RunningText
+}
+
+// DefaultPageSetup returns the page setup used by new documents.
+func DefaultPageSetup() PageSetup {
+	return PageSetup{
+		Width:           65,
+		Length:          66,
+		TopMargin:       3,
+		BottomMargin:    8,
+		HeaderMargin:    2,
+		FooterMargin:    2,
+		PageOffset:      8,
+		FirstPageNumber: 1,
+	}
+}
+
+// BodyLength is the number of lines of body text which fit on a page. It is always at least one.
+func (ps PageSetup) BodyLength() int {
+	n := ps.Length - ps.TopMargin - ps.BottomMargin
+	if n < 1 {
+		return 1
+	}
+	return n
+}
+
+// Check returns an error if the page setup cannot be printed: the page must have room for at least
+// one line of body text and the header and footer, if any, must fit in their margins.
+func (ps PageSetup) Check() error {
+	switch {
+	case ps.Length < 1:
+		return fmt.Errorf("document: page length %d is less than one line", ps.Length)
+	case ps.TopMargin+ps.BottomMargin >= ps.Length:
+		return fmt.Errorf("document: margins leave no room for text on a page of %d lines", ps.Length)
+	case !ps.Header.IsEmpty() && ps.HeaderMargin >= ps.TopMargin:
+		return fmt.Errorf("document: no room for the header in a top margin of %d lines", ps.TopMargin)
+	case !ps.Footer.IsEmpty() && ps.FooterMargin >= ps.BottomMargin:
+		return fmt.Errorf("document: no room for the footer in a bottom margin of %d lines", ps.BottomMargin)
+	}
+	return nil
+}
+
+// HeaderLine is the line within the page on which the header is printed or -1 if there is no room
+// for it in the top margin or on the page.
+func (ps PageSetup) HeaderLine() int {
+	ln := ps.TopMargin - ps.HeaderMargin - 1
+	if ln < 0 || ln >= ps.TopMargin || ln >= ps.Length {
+		return -1
+	}
+	return ln
+}
+
+// FooterLine is the line within the page on which the footer is printed or -1 if there is no room
+// for it in the bottom margin.
+func (ps PageSetup) FooterLine() int {
+	bodyEnd := ps.TopMargin + ps.BodyLength()
+	ln := bodyEnd + ps.FooterMargin
+	if ps.FooterMargin < 0 || ln >= ps.Length {
+		return -1
+	}
+	return ln
+}
+
+// RunningText is a header or footer. Pages with odd numbers use Odd and pages with even numbers use
+// Even. A '#' in the text is replaced by the page number and "\#" gives a literal '#'.
+type RunningText struct {
+	Odd  string
+	Even string
+}
+
+// NewRunningText returns a RunningText which is the same on odd and even pages.
+func NewRunningText(text string) RunningText {
+	return RunningText{Odd: text, Even: text}
+}
+
+// IsEmpty returns true if neither odd nor even pages have any text.
+func (rt RunningText) IsEmpty() bool {
+	return rt.Odd == "" && rt.Even == ""
+}
+
+// ForPage returns the text for the page with the passed number with page number fields expanded.
+func (rt RunningText) ForPage(pageNumber int) string {
+	text := rt.Odd
+	if pageNumber%2 == 0 {
+		text = rt.Even
+	}
+
+	var sb strings.Builder
+	for len(text) > 0 {
+		switch {
+		case strings.HasPrefix(text, `\#`):
+			sb.WriteRune('#')
+			text = text[2:]
+		case text[0] == '#':
+			sb.WriteString(strconv.Itoa(pageNumber))
+			text = text[1:]
+		default:
+			sb.WriteByte(text[0])
+			text = text[1:]
+		}
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,88 @@
+package document
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRunningTextForPage(t *testing.T) {
+	rt := RunningText{Odd: "Page # of \\#1", Even: "# - even"}
+
+	if s := rt.ForPage(3); s != "Page 3 of #1" {
+		t.Errorf("Odd page text: %#v", s)
+	}
+	if s := rt.ForPage(12); s != "12 - even" {
+		t.Errorf("Even page text: %#v", s)
+	}
+}
+
+func TestPageSetupHeaderFooterLines(t *testing.T) {
+	ps := DefaultPageSetup()
+
+	if n := ps.BodyLength(); n != 55 {
+		t.Errorf("Body length: %v", n)
+	}
+	if ln := ps.HeaderLine(); ln != 0 {
+		t.Errorf("Header line: %v", ln)
+	}
+	if ln := ps.FooterLine(); ln != 60 {
+		t.Errorf("Footer line: %v", ln)
+	}
+
+	ps.TopMargin = 1
+	if ln := ps.HeaderLine(); ln != -1 {
+		t.Errorf("Header line with no room: %v", ln)
+	}
+
+	ps = PageSetup{Length: 1, TopMargin: 5}
+	if ln := ps.HeaderLine(); ln != -1 {
+		t.Errorf("Header line below the end of the page: %v", ln)
+	}
+}
+
+func TestReadDotCommands(t *testing.T) {
+	ps, err := DefaultPageSetup().ReadDotCommands(strings.NewReader(
+		"..Report setup\n.he Quarterly report\n.EH  #\n\n.fo Page #\n.pn 3\n.pl 72\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if h := (RunningText{Odd: "Quarterly report", Even: " #"}); ps.Header != h {
+		t.Errorf("Header: %#v", ps.Header)
+	}
+	if ps.Footer != NewRunningText("Page #") {
+		t.Errorf("Footer: %#v", ps.Footer)
+	}
+	if ps.FirstPageNumber != 3 || ps.Length != 72 {
+		t.Errorf("Page numbering and length: %v, %v", ps.FirstPageNumber, ps.Length)
+	}
+
+	for _, line := range []string{".xx 1", ".pl", ".mt -1", "he Title"} {
+		if _, err := DefaultPageSetup().WithDotCommand(line); err == nil {
+			t.Errorf("No error for %q", line)
+		}
+	}
+}
+
+func TestReadDotCommandsChecksPageSetup(t *testing.T) {
+	for _, commands := range []string{
+		".pl 0\n",
+		".pl 10\n.mt 5\n.mb 5\n",
+		".he Title\n.pl 1\n.mt 5\n.hm 0\n",
+		".he Title\n.mt 2\n.hm 2\n",
+		".fo Page #\n.mb 3\n.fm 4\n",
+	} {
+		if _, err := DefaultPageSetup().ReadDotCommands(strings.NewReader(commands)); err == nil {
+			t.Errorf("No error for %q", commands)
+		}
+	}
+
+	// Margins may be changed in any order so long as the result fits.
+	commands := ".mt 40\n.mb 20\n.pl 72\n.hm 0\n"
+	if _, err := DefaultPageSetup().ReadDotCommands(strings.NewReader(commands)); err != nil {
+		t.Errorf("Error for %q: %v", commands, err)
+	}
+	if err := DefaultPageSetup().Check(); err != nil {
+		t.Errorf("Default page setup: %v", err)
+	}
+}
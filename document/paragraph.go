@@ -1,28 +1,107 @@
 package document
 
-import "github.com/deadpixi/rope"
+import (
+	"fmt"
+	"hash/maphash"
+	"io"
+	"sync/atomic"
+
+	"github.com/deadpixi/rope"
+)
 
 type Paragraph struct {
-	text rope.Rope
+	text   rope.Rope
+	format ParagraphFormat
+
+	// contentID is computed when first needed.
+	contentID atomic.Pointer[ContentID]
+
+	// origin records how the paragraph was derived from another, if known.
+	origin *ParagraphOrigin
+}
+
+// ParagraphOrigin records that a paragraph was made by editing another. The bytes [Start, End) of
+// the base paragraph were replaced by Inserted bytes. The format is unchanged.
+type ParagraphOrigin struct {
+	// Base is the content identity of the paragraph which was edited.
+	Base ContentID
+
+	Start, End int
+	Inserted   int
 }
 
+// ContentID identifies the content of a paragraph. Paragraphs with the same text and format have
+// the same ID within a single run of the program.
+type ContentID struct {
+	hash   uint64
+	length int
+}
+
+// contentSeed is the seed used to hash paragraph contents.
+var contentSeed = maphash.MakeSeed()
+
 func newParagraph(text string) *Paragraph {
 	return &Paragraph{text: rope.NewString(text)}
 }
 
 func (p *Paragraph) insertText(at int, text string) *Paragraph {
-	return &Paragraph{text: p.text.InsertString(at, text)}
+	np := &Paragraph{text: p.text.InsertString(at, text), format: p.format}
+
+	// Only record the origin if the base's identity is already known; computing it here would
+	// cost as much as the incremental layout it allows.
+	if id := p.contentID.Load(); id != nil {
+		np.origin = &ParagraphOrigin{Base: *id, Start: at, End: at, Inserted: len(text)}
+	}
+	return np
 }
 
 func (p *Paragraph) split(at int) (*Paragraph, *Paragraph) {
 	lt, rt := p.text.Split(at)
-	return &Paragraph{text: lt}, &Paragraph{text: rt}
+	return &Paragraph{text: lt, format: p.format}, &Paragraph{text: rt, format: p.format}
+}
+
+func (p *Paragraph) withFormat(f ParagraphFormat) *Paragraph {
+	return &Paragraph{text: p.text, format: f}
 }
 
 func (p *Paragraph) String() string {
 	return p.text.String()
 }
 
+// TextSlice returns the text between byte offsets start and end.
+func (p *Paragraph) TextSlice(start, end int) string {
+	return string(p.text.Slice(start, end))
+}
+
+// Origin returns how the paragraph was derived by editing another paragraph. Returns false if this
+// is not known.
+func (p *Paragraph) Origin() (ParagraphOrigin, bool) {
+	if p.origin == nil {
+		return ParagraphOrigin{}, false
+	}
+	return *p.origin, true
+}
+
 func (p *Paragraph) TextLength() int {
 	return p.text.Length()
 }
+
+func (p *Paragraph) Format() ParagraphFormat {
+	return p.format
+}
+
+// ContentID returns the identity of the paragraph's text and format.
+func (p *Paragraph) ContentID() ContentID {
+	if id := p.contentID.Load(); id != nil {
+		return *id
+	}
+
+	var h maphash.Hash
+	h.SetSeed(contentSeed)
+	fmt.Fprintf(&h, "%+v\x00", p.format)
+	io.Copy(&h, p.text.Reader())
+
+	id := &ContentID{hash: h.Sum64(), length: p.text.Length()}
+	p.contentID.Store(id)
+	return *id
+}
@@ -93,7 +93,7 @@ func (p *Point) Forward() *Point {
 	}
 
 	// Do we have another paragraph to move to?
-	if rv.paraIndex + 1 >= rv.d.ParagraphCount() {
+	if rv.paraIndex+1 >= rv.d.ParagraphCount() {
 		// no, move no further
 		return rv
 	}
@@ -145,8 +145,22 @@ func (p *Point) InsertParagraphBreak() *Range {
 
 	start := p.withDoc(nd)
 	end := start.Clone()
-	end.paraIndex ++
+	end.paraIndex++
 	end.textOffset = 0
 
 	return NewRange(start, end)
 }
+
+// SetParagraphFormat changes the format of the paragraph containing the point. If the point is at
+// the end of the document, a new empty paragraph with that format is appended.
+func (p *Point) SetParagraphFormat(f ParagraphFormat) *Point {
+	var nd *Document
+
+	if p.IsDocumentEnd() {
+		nd = p.d.appendParagraph(newParagraph("").withFormat(f))
+	} else {
+		nd = p.d.setParagraph(p.paraIndex, p.Paragraph().withFormat(f))
+	}
+
+	return p.withDoc(nd)
+}
@@ -75,3 +75,58 @@ func TestForwardToDocumentEndDoesNotInsertPara(t *testing.T) {
 	d = d.StartPoint().ForwardN(7).InsertText("xyz").End().Forward().InsertText("X").Document()
 	assertDocString(t, d, "ABC\nDEFxyzX")
 }
+
+func TestSetParagraphFormat(t *testing.T) {
+	d := NewDocument()
+	d = d.StartPoint().InsertText("ABCDEF").Document()
+	d = d.StartPoint().SetParagraphFormat(ParagraphFormat{Alignment: AlignJustify}).Document()
+	d = d.StartPoint().ForwardN(3).InsertParagraphBreak().Document()
+
+	assertDocString(t, d, "ABC\nDEF")
+	for pitr := d.Paragraphs(); !pitr.Done(); {
+		i, p := pitr.Next()
+		if p.Format().Alignment != AlignJustify {
+			t.Errorf("Paragraph %v has alignment %v", i, p.Format().Alignment)
+		}
+	}
+}
+
+func TestLastEdit(t *testing.T) {
+	d := NewDocument()
+	d = d.EndPoint().InsertText("ABC").Document()
+	d = d.EndPoint().InsertText("DEF").Document()
+
+	nd := d.StartPoint().ForwardN(1).InsertParagraphBreak().Document()
+	expected := Edit{Parent: d.Revision(), Start: 0, End: 1, Inserted: 2}
+	if e := nd.LastEdit(); e != expected {
+		t.Errorf("Paragraph break: got %+v, expected %+v", e, expected)
+	}
+
+	nd = d.SetPageSetup(DefaultPageSetup())
+	expected = Edit{Parent: d.Revision()}
+	if e := nd.LastEdit(); e != expected {
+		t.Errorf("Page setup: got %+v, expected %+v", e, expected)
+	}
+	if nd.Revision() == d.Revision() {
+		t.Errorf("Revision unchanged")
+	}
+}
+
+func TestParagraphContentID(t *testing.T) {
+	d := NewDocument()
+	d = d.EndPoint().InsertText("Same").Document()
+	d = d.EndPoint().InsertText("Same").Document()
+	d = d.EndPoint().InsertText("Different").Document()
+
+	if d.GetParagraph(0).ContentID() != d.GetParagraph(1).ContentID() {
+		t.Errorf("Identical paragraphs have different IDs")
+	}
+	if d.GetParagraph(0).ContentID() == d.GetParagraph(2).ContentID() {
+		t.Errorf("Different paragraphs have the same ID")
+	}
+
+	nd := d.StartPoint().SetParagraphFormat(ParagraphFormat{Alignment: AlignCentre}).Document()
+	if nd.GetParagraph(0).ContentID() == d.GetParagraph(0).ContentID() {
+		t.Errorf("Formatting does not change ID")
+	}
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/layout"
+	"github.com/rjw57/rwstar/surface"
+	"github.com/rjw57/rwstar/theme"
+)
+
+// frameRow records what was drawn on one row of text.
+type frameRow struct {
+	// id is the ID of the line drawn on the row. Rows past the end of the document have the zero
+	// ID and blank set.
+	id    layout.LineID
+	blank bool
+
+	// pageEnd is true if the row was marked as the last line of a page.
+	pageEnd bool
+}
+
+// sameAs returns true if a row drawn as r need not be drawn again to show other.
+func (r frameRow) sameAs(other frameRow) bool {
+	if r.blank || other.blank {
+		return r.blank && other.blank
+	}
+	return r.pageEnd == other.pageEnd && r.id != layout.LineID{} && r.id == other.id
+}
+
+// frame remembers what the last redraw drew so that only the rows which have changed are drawn
+// again. The zero value has drawn nothing.
+type frame struct {
+	width, height int
+
+	// ruler describes the ruler as last drawn or is empty if it has not been drawn.
+	ruler string
+
+	// rows are the rows of text as last drawn.
+	rows []frameRow
+}
+
+// invalidate forgets what was drawn so that the next redraw draws everything. It must be called
+// whenever the screen is drawn on by something other than the frame.
+func (fr *frame) invalidate() {
+	*fr = frame{}
+}
+
+// resize forgets what was drawn if the screen size has changed.
+func (fr *frame) resize(s surface.Surface) {
+	if w, h := s.Size(); w != fr.width || h != fr.height {
+		fr.invalidate()
+		fr.width, fr.height = w, h
+		surface.Fill(s, currentTheme.Style(theme.RoleText))
+	}
+}
+
+// drawRuler draws the ruler for f on row y unless it is unchanged.
+func (fr *frame) drawRuler(s surface.Surface, y int, f document.ParagraphFormat) {
+	if ruler := fmt.Sprintf("%+v", f); ruler != fr.ruler {
+		drawRuler(s, y, f)
+		fr.ruler = ruler
+	}
+}
+
+// drawRow draws ln on the row of text with index row, which is on screen row y, unless the same
+// line was drawn there last time. If blank is true, the row is past the end of the document and
+// is cleared. If pageEnd is true, the rest of the row after the line is ruled off to mark the end
+// of a page.
+func (fr *frame) drawRow(s surface.Surface, row, y int, ln layout.Line, blank, pageEnd bool) {
+	r := frameRow{id: ln.ID, blank: blank, pageEnd: pageEnd && !blank}
+	for len(fr.rows) <= row {
+		fr.rows = append(fr.rows, frameRow{})
+	}
+	if fr.rows[row].sameAs(r) {
+		return
+	}
+
+	surface.FillRow(s, 0, y, fr.width, currentTheme.Style(theme.RoleText))
+	if !blank {
+		x := drawLine(s, 0, y, ln)
+		if r.pageEnd {
+			pageBreakStyle := currentTheme.Style(theme.RolePageBreak)
+			for ; x < fr.width; x++ {
+				s.SetContent(x, y, runeHLine, nil, pageBreakStyle)
+			}
+		}
+	}
+	fr.rows[row] = r
+}
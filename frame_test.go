@@ -0,0 +1,49 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/layout"
+	"github.com/rjw57/rwstar/surface"
+)
+
+func TestFrameRedrawsChangedRows(t *testing.T) {
+	g := surface.NewGrid(20, 5)
+
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("first").Document()
+	d = d.EndPoint().InsertParagraphBreak().End().InsertText("second").Document()
+	l, err := layout.NewLayout(d, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetBackgroundWorkers(0)
+
+	fr := &frame{}
+	v := &layout.Viewport{}
+	redraw(g, fr, l, nil, v, d.StartPoint())
+
+	expected := "L-------|-------|--R\nfirst¶\nsecond¶\n\n"
+	if got := g.String(); got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+	if x, y, ok := g.Cursor(); !ok || x != 0 || y != textTop {
+		t.Errorf("Cursor at (%v, %v, %v)", x, y, ok)
+	}
+
+	// Mark each row of text so that we can tell whether it was drawn again.
+	for y := textTop; y < 5; y++ {
+		g.SetContent(19, y, '*', nil, surface.StyleDefault)
+	}
+
+	d = d.PointAt(1, 0).InsertText("the ").Document()
+	l.SetDocument(d)
+	redraw(g, fr, l, nil, v, nil)
+
+	for y, expected := range []rune{'*', ' ', '*', '*'} {
+		if r := g.Cell(19, textTop+y).Mainc; r != expected {
+			t.Errorf("Row %v: got %q, expected %q", y, r, expected)
+		}
+	}
+}
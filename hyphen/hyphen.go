@@ -0,0 +1,247 @@
+// Package hyphen implements Liang's hyphenation algorithm using TeX-style pattern files.
+package hyphen
+
+import (
+	"bufio"
+	"errors"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultLeftMin is the default minimum number of characters before a hyphen.
+	DefaultLeftMin = 2
+
+	// DefaultRightMin is the default minimum number of characters after a hyphen.
+	DefaultRightMin = 3
+)
+
+var ErrUnterminatedGroup = errors.New("Unterminated group in pattern file")
+
+// Hyphenator finds permitted hyphenation points within words.
+type Hyphenator struct {
+	// LeftMin and RightMin give the minimum number of characters before and after a hyphen.
+	LeftMin  int
+	RightMin int
+
+	// patterns maps the letters of each pattern onto its inter-letter values.
+	patterns map[string][]int
+
+	// maxLen is the length in runes of the longest pattern.
+	maxLen int
+
+	// exceptions maps lower-case words onto their hyphenation points as rune indices.
+	exceptions map[string][]int
+}
+
+// New returns a Hyphenator with no patterns or exceptions.
+func New() *Hyphenator {
+	return &Hyphenator{
+		LeftMin:    DefaultLeftMin,
+		RightMin:   DefaultRightMin,
+		patterns:   make(map[string][]int),
+		exceptions: make(map[string][]int),
+	}
+}
+
+// Load reads patterns from r. The input may either be a plain list of patterns, as in the
+// hyph-utf8 ".pat.txt" files, or a TeX file containing \patterns{...} and \hyphenation{...}
+// groups. Comments start with '%' and run to the end of the line.
+func Load(r io.Reader) (*Hyphenator, error) {
+	h := New()
+	if err := h.load(r); err != nil {
+		return nil, err
+	}
+	return h, nil
+}
+
+// LoadFile reads patterns from the named file. See Load.
+func LoadFile(name string) (*Hyphenator, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Load(f)
+}
+
+func (h *Hyphenator) load(r io.Reader) error {
+	var tokens []string
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		ln := s.Text()
+		if i := strings.IndexRune(ln, '%'); i >= 0 {
+			ln = ln[:i]
+		}
+		// Treat braces as separate tokens so that "\patterns{" and "}" are recognised however
+		// they are spaced.
+		ln = strings.NewReplacer("{", " { ", "}", " } ").Replace(ln)
+		tokens = append(tokens, strings.Fields(ln)...)
+	}
+	if err := s.Err(); err != nil {
+		return err
+	}
+
+	// group is the TeX command whose argument is being read, or "" outside of any group.
+	group := ""
+	isTeX := false
+	for i := 0; i < len(tokens); i++ {
+		tok := tokens[i]
+		switch {
+		case strings.HasPrefix(tok, `\`):
+			isTeX = true
+			if i+1 < len(tokens) && tokens[i+1] == "{" {
+				group = tok
+				i++
+			}
+		case tok == "}":
+			group = ""
+		case group == `\patterns` || (!isTeX && group == ""):
+			h.addPattern(tok)
+		case group == `\hyphenation`:
+			h.AddException(tok)
+		}
+	}
+	if group != "" {
+		return ErrUnterminatedGroup
+	}
+
+	return nil
+}
+
+// LoadExceptions reads whitespace-separated exceptions from r. See AddException.
+func (h *Hyphenator) LoadExceptions(r io.Reader) error {
+	s := bufio.NewScanner(r)
+	s.Split(bufio.ScanWords)
+	for s.Scan() {
+		h.AddException(s.Text())
+	}
+	return s.Err()
+}
+
+// addPattern adds a single Liang pattern such as "hen5at" or ".ab4".
+func (h *Hyphenator) addPattern(pattern string) {
+	var letters []rune
+	values := []int{0}
+	for _, r := range pattern {
+		if r >= '0' && r <= '9' {
+			values[len(values)-1] = int(r - '0')
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+		values = append(values, 0)
+	}
+	if len(letters) == 0 {
+		return
+	}
+
+	h.patterns[string(letters)] = values
+	if len(letters) > h.maxLen {
+		h.maxLen = len(letters)
+	}
+}
+
+// AddException adds a word with its permitted hyphenation points marked by '-', such as
+// "ta-ble". A word with no hyphens is never hyphenated. Exceptions override patterns.
+func (h *Hyphenator) AddException(word string) {
+	var letters []rune
+	var points []int
+	for _, r := range word {
+		if r == '-' {
+			points = append(points, len(letters))
+			continue
+		}
+		letters = append(letters, unicode.ToLower(r))
+	}
+	h.exceptions[string(letters)] = points
+}
+
+// WithExceptions returns a copy of the hyphenator with additional exceptions. The patterns are
+// shared with the original.
+func (h *Hyphenator) WithExceptions(words []string) *Hyphenator {
+	nh := *h
+	nh.exceptions = make(map[string][]int, len(h.exceptions)+len(words))
+	for w, p := range h.exceptions {
+		nh.exceptions[w] = p
+	}
+	for _, w := range words {
+		nh.AddException(w)
+	}
+	return &nh
+}
+
+// Hyphenate returns the byte offsets within word at which a hyphen may be inserted. Words which
+// contain anything other than letters are not hyphenated.
+func (h *Hyphenator) Hyphenate(word string) []int {
+	runes := []rune(strings.ToLower(word))
+	if len(runes) != utf8.RuneCountInString(word) {
+		// Case mapping changed the length of the word so rune indices would not match.
+		return nil
+	}
+	for _, r := range runes {
+		if !unicode.IsLetter(r) {
+			return nil
+		}
+	}
+
+	var points []int
+	if p, ok := h.exceptions[string(runes)]; ok {
+		points = p
+	} else {
+		points = h.patternPoints(runes)
+	}
+
+	// Convert rune indices to byte offsets.
+	var offsets []int
+	runeIdx := 0
+	for byteIdx := range word {
+		for len(points) > 0 && points[0] < runeIdx {
+			points = points[1:]
+		}
+		if len(points) > 0 && points[0] == runeIdx && runeIdx > 0 {
+			offsets = append(offsets, byteIdx)
+		}
+		runeIdx++
+	}
+
+	return offsets
+}
+
+// patternPoints applies Liang's algorithm, returning the rune indices before which a hyphen may
+// be inserted.
+func (h *Hyphenator) patternPoints(runes []rune) []int {
+	if len(runes) < h.LeftMin+h.RightMin {
+		return nil
+	}
+
+	dotted := make([]rune, 0, len(runes)+2)
+	dotted = append(append(append(dotted, '.'), runes...), '.')
+
+	// values[i] is the value between dotted[i-1] and dotted[i].
+	values := make([]int, len(dotted)+1)
+	for start := range dotted {
+		for end := start + 1; end <= len(dotted) && end-start <= h.maxLen; end++ {
+			pv, ok := h.patterns[string(dotted[start:end])]
+			if !ok {
+				continue
+			}
+			for i, v := range pv {
+				if v > values[start+i] {
+					values[start+i] = v
+				}
+			}
+		}
+	}
+
+	var points []int
+	for i := h.LeftMin; i <= len(runes)-h.RightMin; i++ {
+		// The value before runes[i] is between dotted[i] and dotted[i+1].
+		if values[i+1]%2 == 1 {
+			points = append(points, i)
+		}
+	}
+	return points
+}
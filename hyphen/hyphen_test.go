@@ -0,0 +1,93 @@
+package hyphen
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const liangPatterns = "hy3ph he2n hena4 hen5at 1na n2at 1tio 2io o2n"
+
+func hyphenated(h *Hyphenator, word string) string {
+	var sb strings.Builder
+	prev := 0
+	for _, offset := range h.Hyphenate(word) {
+		sb.WriteString(word[prev:offset])
+		sb.WriteRune('-')
+		prev = offset
+	}
+	sb.WriteString(word[prev:])
+	return sb.String()
+}
+
+func TestHyphenate(t *testing.T) {
+	h, err := Load(strings.NewReader(liangPatterns))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if s := hyphenated(h, "hyphenation"); s != "hy-phen-ation" {
+		t.Errorf("Hyphenated: %v", s)
+	}
+	if s := hyphenated(h, "Hyphenation"); s != "Hy-phen-ation" {
+		t.Errorf("Hyphenated: %v", s)
+	}
+	if offsets := h.Hyphenate("hyphen1ation"); offsets != nil {
+		t.Errorf("Non-letters hyphenated: %v", offsets)
+	}
+}
+
+func TestExceptions(t *testing.T) {
+	h, err := Load(strings.NewReader(liangPatterns))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	he := h.WithExceptions([]string{"hy-phena-tion", "nation"})
+	if s := hyphenated(he, "hyphenation"); s != "hy-phena-tion" {
+		t.Errorf("Hyphenated: %v", s)
+	}
+	if s := hyphenated(he, "nation"); s != "nation" {
+		t.Errorf("Hyphenated: %v", s)
+	}
+
+	// The original is unchanged.
+	if s := hyphenated(h, "hyphenation"); s != "hy-phen-ation" {
+		t.Errorf("Hyphenated: %v", s)
+	}
+}
+
+func TestRegistry(t *testing.T) {
+	r := NewRegistry("testdata")
+
+	h, err := r.Get("XX")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for word, expected := range map[string]string{
+		"hyphenation": "hy-phen-ation",
+		"table":       "ta-ble",
+		"project":     "pro-ject",
+	} {
+		if s := hyphenated(h, word); s != expected {
+			t.Errorf("Hyphenated: %v", s)
+		}
+	}
+
+	if h2, _ := r.Get("xx"); h2 != h {
+		t.Error("Hyphenator not cached")
+	}
+	if _, err := r.Get("yy"); err == nil {
+		t.Error("Missing language did not fail")
+	}
+}
+
+func TestUnterminatedGroup(t *testing.T) {
+	_, err := Load(strings.NewReader(`\patterns{ a1b`))
+	if err != ErrUnterminatedGroup {
+		t.Errorf("Error: %v", err)
+	}
+	if !reflect.DeepEqual(New().Hyphenate("abc"), []int(nil)) {
+		t.Error("Hyphenator with no patterns hyphenated")
+	}
+}
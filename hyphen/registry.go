@@ -0,0 +1,61 @@
+package hyphen
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// Registry loads hyphenators for languages on demand from a directory of pattern files. Files
+// follow the hyph-utf8 naming convention: patterns for language "en-gb" are read from
+// "hyph-en-gb.pat.txt" or, failing that, "hyph-en-gb.tex". Exceptions are additionally read from
+// "hyph-en-gb.hyp.txt" if present.
+type Registry struct {
+	dir string
+
+	mu          sync.Mutex
+	hyphenators map[string]*Hyphenator
+}
+
+// NewRegistry returns a registry which loads pattern files from dir.
+func NewRegistry(dir string) *Registry {
+	return &Registry{dir: dir, hyphenators: make(map[string]*Hyphenator)}
+}
+
+// Get returns the hyphenator for a language, loading its patterns if necessary.
+func (r *Registry) Get(lang string) (*Hyphenator, error) {
+	lang = strings.ToLower(lang)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if h, ok := r.hyphenators[lang]; ok {
+		return h, nil
+	}
+
+	base := filepath.Join(r.dir, "hyph-"+lang)
+	h, err := LoadFile(base + ".pat.txt")
+	if errors.Is(err, os.ErrNotExist) {
+		h, err = LoadFile(base + ".tex")
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(base + ".hyp.txt")
+	switch {
+	case err == nil:
+		err = h.LoadExceptions(f)
+		f.Close()
+		if err != nil {
+			return nil, err
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return nil, err
+	}
+
+	r.hyphenators[lang] = h
+	return h, nil
+}
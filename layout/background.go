@@ -0,0 +1,199 @@
+package layout
+
+import (
+	"context"
+	"runtime"
+
+	"github.com/rjw57/rwstar/document"
+)
+
+// background holds the state of background layout. It is protected by the layout's mutex.
+type background struct {
+	// workers is the maximum number of paragraphs laid out concurrently. If it is zero, all
+	// paragraphs are laid out when they are first needed.
+	workers int
+
+	// pool is the running pool of workers or nil if none is running.
+	pool *backgroundPool
+
+	// next is the index of the paragraph from which workers look for work. It follows the
+	// paragraphs most recently laid out for display so that nearby paragraphs are laid out first.
+	next int
+
+	// claimed holds the paragraphs currently being laid out by workers.
+	claimed map[*document.Paragraph]bool
+
+	// onIdle is called, without the layout's mutex held, when a pool of workers finishes.
+	onIdle func()
+}
+
+// backgroundPool is a pool of workers laying out paragraphs with the same settings.
+type backgroundPool struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	// active is the number of workers which have not yet exited.
+	active int
+
+	// done is closed when all workers have exited.
+	done chan struct{}
+}
+
+func defaultBackgroundWorkers() int {
+	return runtime.NumCPU()
+}
+
+// cancel stops the running pool, if any. Paragraphs which its workers are laying out are still
+// cached but no more are started.
+func (b *background) cancel() {
+	if b.pool != nil {
+		b.pool.cancel()
+		b.pool = nil
+	}
+}
+
+// estimateLineCount returns an estimate of the number of lines in p, assuming that each byte of
+// text occupies a single cell.
+func (s settings) estimateLineCount(p *document.Paragraph) int {
+	f := p.Format()
+	start, end := f.LineColumns(s.screenWidth/s.textMeasurer().ColumnWidth(), false)
+	lines := p.TextLength()/(end-start) + 1
+
+	n := lines + f.SpaceBefore + f.SpaceAfter
+	for i := 0; i < lines; i++ {
+		n += f.LineSpacing.BlankLinesAfter(i)
+	}
+	return n
+}
+
+// SetBackgroundWorkers sets the maximum number of paragraphs laid out concurrently in the
+// background. If n is zero, paragraphs are only laid out when needed and line counts are always
+// exact. The default is the number of CPUs.
+func (l *Layout) SetBackgroundWorkers(n int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if n == l.background.workers {
+		return
+	}
+	l.invalidate()
+	l.background.workers = n
+}
+
+// SetBackgroundIdleFunc sets a function which is called when background layout finishes. The
+// function is called from a worker goroutine and may, for example, prompt the display to be
+// redrawn with exact line counts.
+func (l *Layout) SetBackgroundIdleFunc(f func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.background.onIdle = f
+}
+
+// WaitForBackgroundLayout blocks until every paragraph of the document has been laid out in the
+// background.
+func (l *Layout) WaitForBackgroundLayout() {
+	for {
+		l.mu.Lock()
+		l.getLineIndex()
+		l.startBackgroundLayout()
+		pool := l.background.pool
+		l.mu.Unlock()
+
+		if pool == nil {
+			return
+		}
+		<-pool.done
+	}
+}
+
+// startBackgroundLayout starts a pool of workers if there are paragraphs with estimated line counts
+// and no pool is running.
+func (l *Layout) startBackgroundLayout() {
+	b := &l.background
+	if b.pool != nil || b.workers <= 0 || l.lineIndex.root.getInexact() == 0 {
+		return
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := &backgroundPool{ctx: ctx, cancel: cancel, active: b.workers, done: make(chan struct{})}
+	b.pool = pool
+	if b.claimed == nil {
+		b.claimed = make(map[*document.Paragraph]bool)
+	}
+
+	for i := 0; i < b.workers; i++ {
+		go l.backgroundWorker(pool)
+	}
+}
+
+// nextBackgroundParagraph returns the next paragraph which a worker should lay out and its index.
+// Paragraphs which have since been laid out are recorded in the line index as they are found.
+// Returns false if there are none.
+func (l *Layout) nextBackgroundParagraph() (int, *document.Paragraph, bool) {
+	b := &l.background
+	if !l.lineIndexValid {
+		return -1, nil, false
+	}
+
+	from, wrapped := b.next, false
+	for {
+		paraIdx, ok := l.lineIndex.FirstInexact(from)
+		if !ok {
+			if wrapped || from == 0 {
+				return -1, nil, false
+			}
+			from, wrapped = 0, true
+			continue
+		}
+		from = paraIdx + 1
+
+		p := l.document.GetParagraph(paraIdx)
+		if pl, ok := l.paraCache.Peek(l.cacheKey(p)); ok {
+			l.setExactLineCount(paraIdx, p, len(pl.lines))
+			continue
+		}
+		if b.claimed[p] {
+			continue
+		}
+
+		b.claimed[p] = true
+		b.next = from
+		return paraIdx, p, true
+	}
+}
+
+// backgroundWorker lays out paragraphs until there are none left or the pool is cancelled.
+func (l *Layout) backgroundWorker(pool *backgroundPool) {
+	l.mu.Lock()
+	for pool.ctx.Err() == nil {
+		paraIdx, p, ok := l.nextBackgroundParagraph()
+		if !ok {
+			break
+		}
+		s, base := l.settings, l.baseLayout(p)
+
+		l.mu.Unlock()
+		pl := s.layoutParagraph(p, base)
+		l.mu.Lock()
+
+		delete(l.background.claimed, p)
+		l.paraCache.Add(cacheKey{content: p.ContentID(), settings: s}, pl)
+		if pool.ctx.Err() == nil {
+			l.setExactLineCount(paraIdx, p, len(pl.lines))
+		}
+	}
+
+	pool.active--
+	finished := pool.active == 0
+	if finished {
+		if l.background.pool == pool {
+			l.background.pool = nil
+		}
+		close(pool.done)
+	}
+	onIdle := l.background.onIdle
+	l.mu.Unlock()
+
+	if finished && pool.ctx.Err() == nil && onIdle != nil {
+		onIdle()
+	}
+}
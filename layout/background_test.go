@@ -0,0 +1,46 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+)
+
+func longTestDocument(paragraphs int) *document.Document {
+	d := document.NewDocument()
+	text := strings.Repeat("The quick brown fox jumps over the lazy dog. ", 5)
+	for i := 0; i < paragraphs; i++ {
+		d = d.EndPoint().InsertText(text).Document()
+	}
+	return d
+}
+
+func TestBackgroundLayout(t *testing.T) {
+	d := longTestDocument(200)
+
+	expected := newTestLayout(t, d, 30)
+	expected.SetBackgroundWorkers(0)
+
+	l := newTestLayout(t, d, 40)
+	l.SetBackgroundWorkers(4)
+
+	// Lines are available while the rest of the document is laid out in the background.
+	if i := l.LineIterator(500); i.Done() {
+		t.Errorf("No line 500")
+	}
+
+	// Changing the width cancels background layout in progress.
+	l.SetScreenWidth(30)
+	l.WaitForBackgroundLayout()
+
+	if l.LineCount() != expected.LineCount() {
+		t.Errorf("Line count: got %v, expected %v", l.LineCount(), expected.LineCount())
+	}
+	for y := 0; y < expected.LineCount(); y += 97 {
+		p := expected.PointForCellLocation(3, y)
+		if got := l.PointForCellLocation(3, y); *got != *p {
+			t.Errorf("Point for line %v: got %v, expected %v", y, got, p)
+		}
+	}
+}
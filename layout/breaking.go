@@ -0,0 +1,340 @@
+package layout
+
+import (
+	"math"
+
+	"github.com/rjw57/rwstar/document"
+)
+
+// BreakStrategy selects the algorithm used to break paragraphs into lines.
+type BreakStrategy int
+
+const (
+	// BreakStrategyGreedy fills each line with as many items as will fit before moving on to the
+	// next. It is fast but can leave very uneven line lengths.
+	BreakStrategyGreedy BreakStrategy = iota
+
+	// BreakStrategyTotalFit uses the Knuth-Plass algorithm to choose the set of breaks which
+	// minimises the total demerits of all lines in the paragraph.
+	BreakStrategyTotalFit
+)
+
+const (
+	// Knuth-Plass parameters, named after their TeX equivalents.
+	kpLinePenalty          = 10
+	kpAdjDemerits          = 100
+	kpDoubleHyphenDemerits = 10000
+	kpTolerance            = 1000
+	kpInfiniteBadness      = 10000
+	kpRaggedStretchFrac    = 3
+)
+
+// Fitness classes for Knuth-Plass. Adjacent lines whose classes differ by more than one incur
+// additional demerits.
+const (
+	fitnessTight = iota
+	fitnessDecent
+	fitnessLoose
+	fitnessVeryLoose
+	fitnessClassCount
+)
+
+// lineShape gives the starting column and width of the lines of a paragraph. The first line may
+// differ from the rest.
+type lineShape struct {
+	firstStart, firstWidth int
+	start, width           int
+}
+
+// newLineShape returns the shape of the lines of a paragraph with format f set in screenWidth cells.
+func newLineShape(f document.ParagraphFormat, screenWidth int) lineShape {
+	var sh lineShape
+	var end int
+	sh.firstStart, end = f.LineColumns(screenWidth, true)
+	sh.firstWidth = end - sh.firstStart
+	sh.start, end = f.LineColumns(screenWidth, false)
+	sh.width = end - sh.start
+	return sh
+}
+
+// forLine returns the starting column and width of the line which starts with the item at
+// startIdx.
+func (sh lineShape) forLine(startIdx int) (start, width int) {
+	if startIdx == 0 {
+		return sh.firstStart, sh.firstWidth
+	}
+	return sh.start, sh.width
+}
+
+// minWidth returns the width of the narrowest line.
+func (sh lineShape) minWidth() int {
+	if sh.firstWidth < sh.width {
+		return sh.firstWidth
+	}
+	return sh.width
+}
+
+// runningWidths returns an array giving the running width of items up to but not including the
+// item at that index. Penalties only take up space if the line is broken at them and so are not
+// included.
+func runningWidths(items []ParagraphItem) []int {
+	widths := make([]int, 1, len(items)+1)
+	for _, item := range items {
+		widths = append(widths, widths[len(widths)-1]+naturalWidth(item))
+	}
+	return widths
+}
+
+// breakWidth returns the width of the line from the item at startIdx when broken at breakIdx.
+func breakWidth(items []ParagraphItem, widths []int, startIdx, breakIdx int) int {
+	w := widths[breakIdx] - widths[startIdx]
+	if items[breakIdx].Type == ParagraphItemTypePenalty {
+		w += items[breakIdx].NaturalWidth
+	}
+	return w
+}
+
+// isProhibitedBreak returns true if the item must never break the line.
+func isProhibitedBreak(item ParagraphItem) bool {
+	switch item.Type {
+	case ParagraphItemTypeBox, ParagraphItemTypeTab:
+		return true
+	}
+	return item.Penalty >= ParagraphItemPenaltyNever
+}
+
+// isForcedBreak returns true if the item must always break the line.
+func isForcedBreak(item ParagraphItem) bool {
+	return item.Type == ParagraphItemTypePenalty && item.Penalty <= ParagraphItemPenaltyAlways
+}
+
+// isEmergencyBreak returns true if the item is a break which should only be used if there is no
+// alternative.
+func isEmergencyBreak(item ParagraphItem) bool {
+	return item.Type == ParagraphItemTypePenalty && item.Penalty >= ParagraphItemPenaltyEmergency
+}
+
+// breakGreedy returns the indices of items at which the line should be broken using a first-fit
+// strategy. Emergency breaks are only used if there is no other feasible break on a line.
+func breakGreedy(items []ParagraphItem, measure lineMeasure, shape lineShape) []int {
+	return breakGreedyFrom(items, measure, shape, 0)
+}
+
+// breakGreedyFrom is like breakGreedy but starts breaking with a line starting at item
+// lineStartIdx. The items before it are ignored.
+func breakGreedyFrom(items []ParagraphItem, measure lineMeasure, shape lineShape, lineStartIdx int) []int {
+	var breaks []int
+
+	lineBreakIdx := -1
+	normalBreakIdx := -1
+	for itemIdx := lineStartIdx; itemIdx < len(items); itemIdx++ {
+		item := items[itemIdx]
+
+		// We can never break on boxes or where breaks are prohibited
+		if isProhibitedBreak(item) {
+			continue
+		}
+
+		// Compute width of a line from current start breaking here.
+		w := measure(lineStartIdx, itemIdx)
+		_, lineWidth := shape.forLine(lineStartIdx)
+
+		// If not feasible, break at the last feasible break, if any. Prefer a normal break but
+		// fall back to the last emergency break if the remainder still does not fit.
+		if w > lineWidth && lineBreakIdx >= lineStartIdx {
+			if normalBreakIdx >= lineStartIdx && normalBreakIdx < lineBreakIdx {
+				breaks = append(breaks, normalBreakIdx)
+				lineStartIdx = normalBreakIdx + 1
+				w = measure(lineStartIdx, itemIdx)
+				_, lineWidth = shape.forLine(lineStartIdx)
+			}
+			if w > lineWidth {
+				breaks = append(breaks, lineBreakIdx)
+				lineStartIdx = lineBreakIdx + 1
+			}
+		}
+
+		// Record this break. If it is still not feasible, the line consists of a single overlong
+		// run of boxes and breaking here is the best we can do.
+		lineBreakIdx = itemIdx
+		if !isEmergencyBreak(item) {
+			normalBreakIdx = itemIdx
+		}
+
+		// If forced, break.
+		if isForcedBreak(item) {
+			breaks = append(breaks, itemIdx)
+			lineStartIdx = itemIdx + 1
+		}
+	}
+
+	return breaks
+}
+
+// breakNode is a feasible breakpoint considered by the Knuth-Plass algorithm.
+type breakNode struct {
+	// index is the index of the item at which the line is broken or -1 for the paragraph start.
+	index int
+
+	fitness  int
+	demerits float64
+	prev     *breakNode
+
+	// flagged is true if the break is at a discretionary hyphen.
+	flagged bool
+}
+
+// badness returns the TeX badness of a line with natural width w set in a line of lineWidth cells
+// given the total stretch available. Returns -1 if the line is overfull and cannot be set.
+func badness(w, lineWidth, stretch int) (int, float64) {
+	switch {
+	case w > lineWidth:
+		return -1, math.Inf(-1)
+	case w == lineWidth:
+		return 0, 0
+	case stretch <= 0:
+		return kpInfiniteBadness, math.Inf(1)
+	}
+
+	r := float64(lineWidth-w) / float64(stretch)
+	b := 100 * r * r * r
+	if b > kpInfiniteBadness {
+		return kpInfiniteBadness, r
+	}
+	return int(math.Round(b)), r
+}
+
+func fitnessClass(r float64) int {
+	switch {
+	case r < -0.5:
+		return fitnessTight
+	case r <= 0.5:
+		return fitnessDecent
+	case r <= 1:
+		return fitnessLoose
+	}
+	return fitnessVeryLoose
+}
+
+// isTotalFitBreakpoint returns true if the line may be broken at the item with the passed index.
+// As in TeX, glue is only a breakpoint if it immediately follows a box.
+func isTotalFitBreakpoint(items []ParagraphItem, idx int) bool {
+	if isProhibitedBreak(items[idx]) {
+		return false
+	}
+	switch items[idx].Type {
+	case ParagraphItemTypePenalty:
+		return true
+	case ParagraphItemTypeGlue:
+		return idx > 0 && items[idx-1].Type == ParagraphItemTypeBox
+	}
+	return false
+}
+
+// breakTotalFit returns the indices of items at which the line should be broken using the
+// Knuth-Plass total-fit algorithm. If justify is true, each glue item may stretch by its natural
+// width per unit of adjustment ratio. Otherwise lines are set ragged right and the stretch
+// available to each line is a fixed fraction of the line width. If there is no feasible set of
+// breaks, the greedy strategy is used instead.
+func breakTotalFit(items []ParagraphItem, measure lineMeasure, shape lineShape, justify bool) []int {
+	// glueWidths gives the natural width of the glue items up to but not including the item at
+	// that index.
+	glueWidths := make([]int, 1, len(items)+1)
+	for _, item := range items {
+		n := glueWidths[len(glueWidths)-1]
+		if item.Type == ParagraphItemTypeGlue {
+			n += item.NaturalWidth
+		}
+		glueWidths = append(glueWidths, n)
+	}
+
+	active := []*breakNode{{index: -1, fitness: fitnessDecent}}
+	var last *breakNode
+
+	for b, item := range items {
+		if !isTotalFitBreakpoint(items, b) {
+			continue
+		}
+
+		forced := isForcedBreak(item)
+		var candidates [fitnessClassCount]*breakNode
+		nextActive := active[:0]
+
+		for _, a := range active {
+			w := measure(a.index+1, b)
+			_, lineWidth := shape.forLine(a.index + 1)
+			stretch := lineWidth / kpRaggedStretchFrac
+			if justify {
+				stretch = glueWidths[b] - glueWidths[a.index+1]
+			}
+
+			bad, r := badness(w, lineWidth, stretch)
+			if forced && bad > 0 {
+				// A forced break ends the line early; treat it as if filled with infinite glue.
+				bad, r = 0, 0
+			}
+
+			// Nodes which would give overfull lines can never be used again, nor can nodes before
+			// a forced break.
+			if bad >= 0 && !forced {
+				nextActive = append(nextActive, a)
+			}
+			if bad < 0 || bad > kpTolerance {
+				continue
+			}
+
+			d := float64(kpLinePenalty + bad)
+			d *= d
+			if p := float64(item.Penalty); item.Type == ParagraphItemTypePenalty && !forced {
+				if p >= 0 {
+					d += p * p
+				} else {
+					d -= p * p
+				}
+			}
+			fitness := fitnessClass(r)
+			if fitness-a.fitness > 1 || a.fitness-fitness > 1 {
+				d += kpAdjDemerits
+			}
+			flagged := item.IsDiscretionary()
+			if flagged && a.flagged {
+				d += kpDoubleHyphenDemerits
+			}
+			d += a.demerits
+
+			if c := candidates[fitness]; c == nil || d < c.demerits {
+				candidates[fitness] = &breakNode{
+					index: b, fitness: fitness, demerits: d, prev: a, flagged: flagged,
+				}
+			}
+		}
+
+		active = nextActive
+		for _, c := range candidates {
+			if c == nil {
+				continue
+			}
+			active = append(active, c)
+			if b == len(items)-1 && (last == nil || c.demerits < last.demerits) {
+				last = c
+			}
+		}
+
+		if len(active) == 0 {
+			break
+		}
+	}
+
+	if last == nil {
+		return breakGreedy(items, measure, shape)
+	}
+
+	var breaks []int
+	for n := last; n.prev != nil; n = n.prev {
+		breaks = append(breaks, n.index)
+	}
+	for i, j := 0, len(breaks)-1; i < j; i, j = i+1, j-1 {
+		breaks[i], breaks[j] = breaks[j], breaks[i]
+	}
+	return breaks
+}
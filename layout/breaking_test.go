@@ -0,0 +1,104 @@
+package layout
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+)
+
+func breakLinesText(items []ParagraphItem, breaks []int) []string {
+	var lines []string
+	start := 0
+	for _, b := range breaks {
+		lines = append(lines, lineText(setLine(items[start:b], 0, 0, document.ParagraphFormat{}, false)))
+		start = b + 1
+	}
+	return lines
+}
+
+func testItems(text string) []ParagraphItem {
+	items := appendTextParagraphItems(nil, text, 0, nil)
+	items = append(items, ParagraphItem{
+		Type:        ParagraphItemTypePenalty,
+		StartOffset: len(text),
+		EndOffset:   len(text),
+		Penalty:     ParagraphItemPenaltyAlways,
+	})
+	setNaturalWidths(items, CellMeasurer{})
+	return items
+}
+
+func testMeasure(items []ParagraphItem) lineMeasure {
+	return newLineMeasure(items, lineShape{}, document.ParagraphFormat{})
+}
+
+// testShape returns the shape of a paragraph whose lines all start at column zero and are width
+// cells wide.
+func testShape(width int) lineShape {
+	return lineShape{firstWidth: width, width: width}
+}
+
+func TestBreakGreedy(t *testing.T) {
+	items := testItems("aaa bb cc ddddd")
+	lines := breakLinesText(items, breakGreedy(items, testMeasure(items), testShape(6)))
+	expected := []string{"aaa bb", "cc", "ddddd"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Lines: %#v", lines)
+	}
+}
+
+func TestBreakGreedyOverlong(t *testing.T) {
+	items := testItems("a bbbbbbbb c")
+	lines := breakLinesText(items, breakGreedy(items, testMeasure(items), testShape(4)))
+	expected := []string{"a", "bbbbbbbb", "c"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Lines: %#v", lines)
+	}
+}
+
+func TestBreakTotalFit(t *testing.T) {
+	items := testItems("aaa bb cc ddddd")
+	lines := breakLinesText(items, breakTotalFit(items, testMeasure(items), testShape(6), false))
+	expected := []string{"aaa", "bb cc", "ddddd"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Lines: %#v", lines)
+	}
+}
+
+func TestBreakTotalFitFallsBackToGreedy(t *testing.T) {
+	items := testItems("a bbbbbbbb c")
+	lines := breakLinesText(items, breakTotalFit(items, testMeasure(items), testShape(4), false))
+	expected := []string{"a", "bbbbbbbb", "c"}
+	if !reflect.DeepEqual(lines, expected) {
+		t.Errorf("Lines: %#v", lines)
+	}
+}
+
+func TestNoBreakSpace(t *testing.T) {
+	for _, text := range []string{"a 10\u00a0km", "a 10\u202fkm"} {
+		items := testItems(text)
+		expected := []string{"a", "10 km"}
+
+		lines := breakLinesText(items, breakGreedy(items, testMeasure(items), testShape(5)))
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("Greedy lines for %#v: %#v", text, lines)
+		}
+
+		lines = breakLinesText(items, breakTotalFit(items, testMeasure(items), testShape(5), false))
+		if !reflect.DeepEqual(lines, expected) {
+			t.Errorf("Total-fit lines for %#v: %#v", text, lines)
+		}
+	}
+}
+
+func TestWordJoinerIsNeverBreak(t *testing.T) {
+	items := testItems("ab\u2060cd")
+	if len(items) != 4 || items[1].Type != ParagraphItemTypePenalty ||
+		items[1].Penalty != ParagraphItemPenaltyNever {
+		t.Fatalf("Items: %#v", items)
+	}
+	if items[2].StartOffset != 5 {
+		t.Errorf("Start offset of item after word joiner: %v", items[2].StartOffset)
+	}
+}
@@ -2,26 +2,34 @@ package layout
 
 import (
 	"errors"
+	"math"
 	"strings"
+	"sync"
 
-	"github.com/gdamore/tcell/v2"
 	"github.com/hashicorp/golang-lru/v2"
+	"github.com/rivo/uniseg"
 	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/hyphen"
+	"github.com/rjw57/rwstar/surface"
 )
 
 const cacheChunkSizeLog2 = 5
 const cacheChunkSize = 1 << cacheChunkSizeLog2
 
+// cacheLayoutsPerParagraph is the number of layouts of each paragraph for which there is room in
+// the cache, so that switching between a few widths or settings reuses earlier results.
+const cacheLayoutsPerParagraph = 4
+
+// cacheCapacity returns the capacity of the cache for a document with n paragraphs.
+func cacheCapacity(n int) int {
+	return (cacheLayoutsPerParagraph*n + cacheChunkSize) & ^(cacheChunkSize - 1)
+}
+
 var (
 	ErrPointIsFromDifferentDocument = errors.New("Point is from different document")
 	ErrPointNotFound                = errors.New("Point not found")
 )
 
-var (
-	StyleNormal = tcell.StyleDefault.Background(tcell.ColorDarkBlue).Foreground(tcell.ColorLightGray)
-	StyleMarkup = StyleNormal.Foreground(tcell.ColorDarkCyan)
-)
-
 type Cell struct {
 	// Mainc represents the main rune for this cell. If 0, the cell is the rightmost companion to a
 	// wide character and should not be rendered.
@@ -31,7 +39,7 @@ type Cell struct {
 	Combc []rune
 
 	// Style describes how to render the cell.
-	Style tcell.Style
+	Style surface.Style
 
 	// StartOffset gives the location within the parent paragraph of the cell's contents.
 	StartOffset int
@@ -40,92 +48,498 @@ type Cell struct {
 	EndOffset int
 }
 
-type Line []ParagraphItem
+// Line is a single laid out line of a paragraph.
+type Line struct {
+	// Items are the paragraph items on this line. Each item's Width has been set.
+	Items []ParagraphItem
+
+	// Indent is the number of blank cells to the left of the first item.
+	Indent int
+
+	// ID identifies the line's content. See LineID.
+	ID LineID
+}
+
+// LineID identifies the appearance of a laid out line. Lines with the same non-zero ID are drawn
+// identically, so a front end need not redraw a line whose ID has not changed. Lines which were
+// not made by a Layout have the zero ID.
+type LineID struct {
+	key   cacheKey
+	index int
+}
 
 func (l Line) StartOffset() int {
-	if len(l) == 0 {
+	if len(l.Items) == 0 {
 		return -1
 	}
-	return l[0].StartOffset
+	return l.Items[0].StartOffset
 }
 
 func (l Line) EndOffset() int {
-	if len(l) == 0 {
+	if len(l.Items) == 0 {
 		return -1
 	}
-	return l[len(l)-1].EndOffset
+	return l.Items[len(l.Items)-1].EndOffset
+}
+
+// String returns the line as it appears on screen, including markup.
+func (l Line) String() string {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", l.Indent))
+	for _, item := range l.Items {
+		switch item.Type {
+		case ParagraphItemTypeBox:
+			sb.WriteString(item.Text)
+		case ParagraphItemTypeGlue, ParagraphItemTypeTab:
+			w := item.Width
+			if item.Mark != "" && w > 0 {
+				sb.WriteString(item.Mark)
+				w--
+			}
+			sb.WriteString(strings.Repeat(" ", w))
+		}
+	}
+	return sb.String()
+}
+
+// IsBlank returns true if the line has no items, as is the case for blank lines added by line and
+// paragraph spacing.
+func (l Line) IsBlank() bool {
+	return len(l.Items) == 0
+}
+
+// CellForOffset returns the cell within the line which represents the passed paragraph offset.
+// Offsets at or after the end of the line give the cell of any markup at that offset, such as the
+// pilcrow at the end of a paragraph, or else the cell after the last item. Returns false if the
+// offset is before the start of the line or the line is blank.
+func (l Line) CellForOffset(offset int) (int, bool) {
+	if l.IsBlank() || offset < l.StartOffset() {
+		return -1, false
+	}
+
+	x := l.Indent
+	for _, item := range l.Items {
+		if item.StartOffset <= offset && item.EndOffset > offset {
+			if item.showsSource() {
+				x += uniseg.StringWidth(item.Text[:offset-item.StartOffset])
+			}
+			return x, true
+		}
+		x += item.Width
+	}
+
+	if offset < l.EndOffset() {
+		return -1, false
+	}
+
+	x = l.Indent
+	for _, item := range l.Items {
+		if item.StartOffset == offset && item.EndOffset == offset && item.Width > 0 {
+			return x, true
+		}
+		x += item.Width
+	}
+	return x, true
+}
+
+// OffsetForCell returns the paragraph offset of the grapheme cluster boundary nearest to cell x.
+// Cells within glue, tabs and wide characters snap to whichever side is closer. Cells before the
+// line give its start and cells after it give its end. Markup, such as the pilcrow at the end of a
+// paragraph, gives the offset it marks.
+func (l Line) OffsetForCell(x int) int {
+	cx := l.Indent
+	if x < cx {
+		return l.StartOffset()
+	}
+
+	for _, item := range l.Items {
+		if item.Width == 0 || x >= cx+item.Width {
+			cx += item.Width
+			continue
+		}
+
+		switch {
+		case item.StartOffset == item.EndOffset:
+			return item.StartOffset
+		case item.showsSource():
+			state := -1
+			text := item.Text
+			offset := item.StartOffset
+			for len(text) > 0 {
+				var cluster string
+				var w int
+				cluster, text, w, state = uniseg.FirstGraphemeClusterInString(text, state)
+				if x < cx+w {
+					if 2*(x-cx)+1 <= w {
+						return offset
+					}
+					return offset + len(cluster)
+				}
+				cx += w
+				offset += len(cluster)
+			}
+			return item.EndOffset
+		default:
+			if 2*(x-cx)+1 <= item.Width {
+				return item.StartOffset
+			}
+			return item.EndOffset
+		}
+	}
+
+	return l.EndOffset()
 }
 
 type Lines []Line
 
+// EmergencyBreaking controls how boxes, such as long URLs, which are wider than a line are
+// handled.
+type EmergencyBreaking struct {
+	// Enabled allows overlong boxes to be broken between any two grapheme clusters. Otherwise they
+	// overflow the line.
+	Enabled bool
+
+	// Marker, if non-empty, is rendered as markup at the end of a line broken in this way.
+	Marker string
+}
+
+// settings are the parameters which, together with a paragraph, determine its layout.
+type settings struct {
+	screenWidth       int
+	breakStrategy     BreakStrategy
+	emergencyBreaking EmergencyBreaking
+
+	// hyphenator is the hyphenator for the document or nil if it is not hyphenated.
+	hyphenator *hyphen.Hyphenator
+
+	// measurer measures text. If nil, CellMeasurer is used.
+	measurer *measurerRef
+
+	// view gives the formatting marks which are shown.
+	view ViewOptions
+}
+
+// cacheKey identifies a laid out paragraph. Paragraphs with the same content laid out with the
+// same settings share a key.
+type cacheKey struct {
+	content  document.ContentID
+	settings settings
+}
+
+// CacheStats counts lookups in the cache of laid out paragraphs.
+type CacheStats struct {
+	Hits   uint64
+	Misses uint64
+}
+
+// Layout lays out a document for display. It is safe for concurrent use. Paragraphs which are not
+// needed immediately are laid out in the background by a pool of workers.
 type Layout struct {
-	document    *document.Document
-	screenWidth int
-	paraCache   *lru.Cache[*document.Paragraph, Lines]
+	mu sync.Mutex
+
+	document *document.Document
+	settings settings
+
+	// paraCache holds laid out paragraphs. Its capacity only grows.
+	paraCache         *lru.Cache[cacheKey, *paragraphLayout]
+	paraCacheCapacity int
+	cacheStats        CacheStats
+
+	// hyphenRegistry supplies patterns for the document's language.
+	hyphenRegistry *hyphen.Registry
+
+	// lineIndex holds the number of lines in each paragraph of the document if lineIndexValid is
+	// true. It is updated incrementally as the document is edited.
+	lineIndex      lineIndex
+	lineIndexValid bool
+
+	background background
+}
+
+// invalidate discards the line index and cancels any background layout. Laid out paragraphs are
+// kept in the cache since they are keyed by the settings used to lay them out.
+func (l *Layout) invalidate() {
+	l.lineIndexValid = false
+	l.background.cancel()
+}
+
+// cacheKey returns the key under which p laid out with the current settings is cached.
+func (l *Layout) cacheKey(p *document.Paragraph) cacheKey {
+	return cacheKey{content: p.ContentID(), settings: l.settings}
+}
+
+// CacheStats returns the number of hits and misses in the cache of laid out paragraphs.
+func (l *Layout) CacheStats() CacheStats {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cacheStats
+}
+
+// paragraphLineCount returns the line count of p for the line index. Paragraphs which have not
+// been laid out are given an estimate if background layout is enabled.
+func (l *Layout) paragraphLineCount(p *document.Paragraph) lineCount {
+	if pl, ok := l.paraCache.Peek(l.cacheKey(p)); ok {
+		return lineCount{lines: len(pl.lines), exact: true}
+	}
+	if l.background.workers > 0 {
+		return lineCount{lines: l.settings.estimateLineCount(p)}
+	}
+	return lineCount{lines: len(l.getParagraphLines(p)), exact: true}
+}
+
+// getLineIndex returns the line index for the document, building it if necessary.
+func (l *Layout) getLineIndex() lineIndex {
+	if l.lineIndexValid {
+		return l.lineIndex
+	}
+
+	counts := make([]lineCount, 0, l.document.ParagraphCount())
+	pitr := l.document.Paragraphs()
+	for !pitr.Done() {
+		_, p := pitr.Next()
+		counts = append(counts, l.paragraphLineCount(p))
+	}
+
+	l.lineIndex = newLineIndex(counts)
+	l.lineIndexValid = true
+	l.startBackgroundLayout()
+	return l.lineIndex
+}
+
+// updateLineIndex updates the line index for an edit which derived the current document from the
+// document with revision parent. If the edit does not apply to that document the index is
+// discarded.
+func (l *Layout) updateLineIndex(parent uint64) {
+	if !l.lineIndexValid {
+		return
+	}
+
+	e := l.document.LastEdit()
+	if e.Parent != parent {
+		l.lineIndexValid = false
+		return
+	}
+
+	counts := make([]lineCount, e.Inserted)
+	for i := range counts {
+		counts[i] = l.paragraphLineCount(l.document.GetParagraph(e.Start + i))
+	}
+	l.lineIndex = l.lineIndex.Splice(e.Start, e.End, counts)
+	l.startBackgroundLayout()
+}
+
+// setExactLineCount records the number of lines in paragraph p, which was at index paraIdx when it
+// was laid out. Nothing is recorded if the paragraph has since moved.
+func (l *Layout) setExactLineCount(paraIdx int, p *document.Paragraph, lines int) {
+	if !l.lineIndexValid || paraIdx >= l.document.ParagraphCount() || l.document.GetParagraph(paraIdx) != p {
+		return
+	}
+	count := lineCount{lines: lines, exact: true}
+	if l.lineIndex.At(paraIdx) != count {
+		l.lineIndex = l.lineIndex.Splice(paraIdx, paraIdx+1, []lineCount{count})
+	}
 }
 
 func (l *Layout) getParagraphLines(p *document.Paragraph) Lines {
-	ls, ok := l.paraCache.Get(p)
-	if ok {
-		return ls
+	key := l.cacheKey(p)
+	if pl, ok := l.paraCache.Get(key); ok {
+		l.cacheStats.Hits++
+		return pl.lines
 	}
+	l.cacheStats.Misses++
+
+	pl := l.settings.layoutParagraph(p, l.baseLayout(p))
+	l.paraCache.Add(key, pl)
+	return pl.lines
+}
 
-	ls = l.renderParagraphLines(p)
+// baseLayout returns the cached layout of the paragraph p was derived from, if any.
+func (l *Layout) baseLayout(p *document.Paragraph) *paragraphLayout {
+	o, ok := p.Origin()
+	if !ok {
+		return nil
+	}
+	pl, _ := l.paraCache.Peek(cacheKey{content: o.Base, settings: l.settings})
+	return pl
+}
 
-	l.paraCache.Add(p, ls)
+// paragraphLinesAt returns the lines of paragraph p at index paraIdx, laying it out immediately if
+// necessary. The line index is updated with the exact line count and background layout continues
+// from the following paragraph.
+func (l *Layout) paragraphLinesAt(paraIdx int, p *document.Paragraph) Lines {
+	ls := l.getParagraphLines(p)
+	l.setExactLineCount(paraIdx, p, len(ls))
+	l.background.next = paraIdx + 1
 	return ls
 }
 
+// findLine returns the index of the paragraph containing line lineIdx, the paragraph's lines and
+// the index of the line within them. Returns false if the line is beyond the end of the layout.
+func (l *Layout) findLine(lineIdx int) (int, Lines, int, bool) {
+	for {
+		li := l.getLineIndex()
+		paraIdx, firstLineIdx, ok := li.Find(lineIdx)
+		if !ok {
+			return -1, nil, -1, false
+		}
+
+		// Laying out the paragraph may correct an estimated line count, moving the line.
+		lns := l.paragraphLinesAt(paraIdx, l.document.GetParagraph(paraIdx))
+		if l.lineIndex == li {
+			return paraIdx, lns, lineIdx - firstLineIdx, true
+		}
+	}
+}
+
 func NewLayout(d *document.Document, screenWidth int) (*Layout, error) {
-	paraCache, err := lru.New[*document.Paragraph, Lines](cacheChunkSize)
+	capacity := cacheCapacity(d.ParagraphCount())
+	paraCache, err := lru.New[cacheKey, *paragraphLayout](capacity)
 	if err != nil {
 		return nil, err
 	}
 	return &Layout{
-		document:    d,
-		screenWidth: screenWidth,
-		paraCache:   paraCache,
+		document: d,
+		settings: settings{
+			screenWidth:       screenWidth,
+			emergencyBreaking: EmergencyBreaking{Enabled: true},
+			view:              DefaultViewOptions(),
+		},
+		paraCache:         paraCache,
+		paraCacheCapacity: capacity,
+		background:        background{workers: defaultBackgroundWorkers()},
 	}, nil
 }
 
 func (l *Layout) ScreenWidth() int {
-	return l.screenWidth
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings.screenWidth
 }
 
 func (l *Layout) SetScreenWidth(screenWidth int) {
-	if screenWidth == l.screenWidth {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if screenWidth == l.settings.screenWidth {
+		return
+	}
+	l.invalidate()
+	l.settings.screenWidth = screenWidth
+}
+
+func (l *Layout) BreakStrategy() BreakStrategy {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings.breakStrategy
+}
+
+func (l *Layout) SetBreakStrategy(bs BreakStrategy) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if bs == l.settings.breakStrategy {
+		return
+	}
+	l.invalidate()
+	l.settings.breakStrategy = bs
+}
+
+func (l *Layout) EmergencyBreaking() EmergencyBreaking {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings.emergencyBreaking
+}
+
+func (l *Layout) SetEmergencyBreaking(eb EmergencyBreaking) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if eb == l.settings.emergencyBreaking {
 		return
 	}
-	l.paraCache.Purge()
-	l.screenWidth = screenWidth
+	l.invalidate()
+	l.settings.emergencyBreaking = eb
 }
 
 func (l *Layout) Document() *document.Document {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return l.document
 }
 
 func (l *Layout) SetDocument(d *document.Document) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	if d == l.document {
 		return
 	}
-	l.paraCache.Resize((d.ParagraphCount() + cacheChunkSize - 1) & ^(cacheChunkSize - 1))
+	if capacity := cacheCapacity(d.ParagraphCount()); capacity > l.paraCacheCapacity {
+		l.paraCache.Resize(capacity)
+		l.paraCacheCapacity = capacity
+	}
+	hyphenationChanged := !d.Hyphenation().Equal(l.document.Hyphenation())
+	parent := l.document.Revision()
 	l.document = d
+	if hyphenationChanged {
+		l.updateHyphenator()
+	}
+	l.updateLineIndex(parent)
+}
+
+// SetHyphenationRegistry sets the registry from which hyphenation patterns for the document's
+// language are loaded. Passing nil disables automatic hyphenation.
+func (l *Layout) SetHyphenationRegistry(r *hyphen.Registry) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if r == l.hyphenRegistry {
+		return
+	}
+	l.hyphenRegistry = r
+	l.updateHyphenator()
+}
+
+// updateHyphenator selects the hyphenator for the document's hyphenation settings and purges any
+// cached layout. Languages for which no patterns can be loaded are not hyphenated.
+func (l *Layout) updateHyphenator() {
+	l.invalidate()
+	l.settings.hyphenator = nil
+
+	hs := l.document.Hyphenation()
+	if l.hyphenRegistry == nil || hs.Language == "" {
+		return
+	}
+	h, err := l.hyphenRegistry.Get(hs.Language)
+	if err != nil {
+		return
+	}
+	l.settings.hyphenator = h
+	if len(hs.Exceptions) > 0 {
+		l.settings.hyphenator = h.WithExceptions(hs.Exceptions)
+	}
+}
+
+// LineCount returns the total number of lines in the layout. While paragraphs are being laid out
+// in the background, this includes estimates for those which have not yet been laid out.
+func (l *Layout) LineCount() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.getLineIndex().LineCount()
 }
 
 func (l *Layout) LineIterator(startLineIndex int) *LineIterator {
+	l.mu.Lock()
+	defer l.mu.Unlock()
 	return newLineIterator(l, startLineIndex)
 }
 
 func (l *Layout) String() string {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	sb := strings.Builder{}
 
 	pitr := l.document.Paragraphs()
 	for !pitr.Done() {
 		_, p := pitr.Next()
 		for _, ln := range l.getParagraphLines(p) {
-			for _, item := range ln {
-				sb.WriteString(item.Text)
-			}
+			sb.WriteString(ln.String())
 			sb.WriteRune('\n')
 		}
 	}
@@ -133,7 +547,12 @@ func (l *Layout) String() string {
 	return sb.String()
 }
 
+// CellLocationForPoint returns the screen cell at which the passed point is shown. The end of the
+// document is shown at the start of the line after the last line.
 func (l *Layout) CellLocationForPoint(p *document.Point) (int, int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
 	if p.Document() != l.document {
 		return -1, -1, ErrPointIsFromDifferentDocument
 	}
@@ -142,76 +561,97 @@ func (l *Layout) CellLocationForPoint(p *document.Point) (int, int, error) {
 		return 0, 0, nil
 	}
 
-	pitr := p.Document().Paragraphs()
-	lineIndex := 0
 	targetParaIdx := p.ParagraphIndex()
 	targetOffset := p.TextOffset()
 
-	for !pitr.Done() {
-		paraIdx, para := pitr.Next()
-		lns := l.getParagraphLines(para)
-
-		if paraIdx == targetParaIdx {
-			for lnIdx, ln := range lns {
-				// TODO: cursor at end of paragraph
-				//if targetOffset >= para.TextLength() && ln.EndOffset >= targetOffset {
-				//	return len(ln.Cells), lineIndex + lnIdx, nil
-				//}
-
-				if ln.StartOffset() > targetOffset || ln.EndOffset() <= targetOffset {
-					continue
-				}
-
-				x := 0
-				for _, item := range ln {
-					if item.StartOffset <= targetOffset && item.EndOffset > targetOffset {
-						return x + targetOffset - item.StartOffset, lineIndex + lnIdx, nil
-					}
-					switch item.Type {
-					case ParagraphItemTypeBox:
-						x += item.CellCount()
-					case ParagraphItemTypeGlue:
-						x += 1
-					}
-				}
-			}
-			return -1, -1, ErrPointNotFound
+	if targetParaIdx >= l.document.ParagraphCount() {
+		return 0, l.getLineIndex().LineCount(), nil
+	}
+	l.getLineIndex()
+	lns := l.paragraphLinesAt(targetParaIdx, l.document.GetParagraph(targetParaIdx))
+	lineIndex := l.lineIndex.LinesBefore(targetParaIdx)
+
+	// Lines are in order of offset so the last line which can show the offset is used.
+	x, y := -1, -1
+	for lnIdx, ln := range lns {
+		if cx, ok := ln.CellForOffset(targetOffset); ok {
+			x, y = cx, lineIndex+lnIdx
 		}
+	}
+	if y < 0 {
+		return -1, -1, ErrPointNotFound
+	}
+	return x, y, nil
+}
+
+// PointForCellLocation returns the point shown nearest to screen cell (x, y). The point is always
+// at a grapheme cluster boundary. Rows above or below the text give points on the first or last
+// line and blank rows give points on the nearest line of text in the same paragraph.
+func (l *Layout) PointForCellLocation(x, y int) *document.Point {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.document.ParagraphCount() == 0 {
+		return l.document.StartPoint()
+	}
+	if y < 0 {
+		y, x = 0, -1
+	}
+
+	paraIdx, lns, lnIdx, ok := l.findLine(y)
+	if !ok {
+		// Rows below the text give the end of the last line.
+		paraIdx = l.document.ParagraphCount() - 1
+		lns = l.paragraphLinesAt(paraIdx, l.document.GetParagraph(paraIdx))
+		lnIdx, x = len(lns)-1, math.MaxInt
+	}
 
-		lineIndex += len(lns)
+	// Search outwards from blank rows for a line of text.
+	for d := 1; lns[lnIdx].IsBlank() && d < len(lns); d++ {
+		if lnIdx-d >= 0 && !lns[lnIdx-d].IsBlank() {
+			lnIdx -= d
+		} else if lnIdx+d < len(lns) && !lns[lnIdx+d].IsBlank() {
+			lnIdx += d
+		}
+	}
+	if lns[lnIdx].IsBlank() {
+		return l.document.PointAt(paraIdx, 0)
 	}
 
-	return -1, -1, ErrPointNotFound
+	return l.document.PointAt(paraIdx, lns[lnIdx].OffsetForCell(x))
 }
 
+// LineIterator iterates over the lines of a layout. Paragraphs are laid out as they are reached.
+// The iterator continues to return lines of the document which was current when it was created.
 type LineIterator struct {
 	layout        *Layout
 	lineIndex     int
 	paraIterator  document.ParagraphIterator
+	paraIndex     int
 	lines         Lines
 	paraLineIndex int
 }
 
 func newLineIterator(layout *Layout, startLineIndex int) *LineIterator {
-	i := &LineIterator{
-		layout:       layout,
-		paraIterator: layout.document.Paragraphs(),
-		lineIndex:    0,
-	}
-
-	for !i.paraIterator.Done() {
-		_, para := i.paraIterator.Next()
-		i.lines = i.layout.getParagraphLines(para)
-
-		if i.lineIndex+len(i.lines) <= startLineIndex {
-			i.lineIndex += len(i.lines)
-		} else {
-			i.paraLineIndex = startLineIndex - i.lineIndex
-			i.lineIndex += i.paraLineIndex
-			break
-		}
+	if startLineIndex < 0 {
+		startLineIndex = 0
 	}
 
+	i := &LineIterator{layout: layout}
+
+	paraIdx, lns, lnIdx, ok := layout.findLine(startLineIndex)
+	if !ok {
+		n := layout.document.ParagraphCount()
+		i.paraIterator = layout.document.ParagraphSlice(n, n)
+		i.lineIndex = layout.lineIndex.LineCount()
+		return i
+	}
+
+	i.paraIterator = layout.document.ParagraphSlice(paraIdx+1, layout.document.ParagraphCount())
+	i.paraIndex = paraIdx
+	i.lines = lns
+	i.paraLineIndex = lnIdx
+	i.lineIndex = startLineIndex
 	return i
 }
 
@@ -228,10 +668,15 @@ func (i *LineIterator) Next() (int, Line) {
 
 	i.paraLineIndex++
 	i.lineIndex++
-	for !i.paraIterator.Done() && i.paraLineIndex >= len(i.lines) {
-		_, para := i.paraIterator.Next()
-		i.lines = i.layout.getParagraphLines(para)
-		i.paraLineIndex = 0
+	if i.paraLineIndex >= len(i.lines) && !i.paraIterator.Done() {
+		i.layout.mu.Lock()
+		for !i.paraIterator.Done() && i.paraLineIndex >= len(i.lines) {
+			_, para := i.paraIterator.Next()
+			i.paraIndex++
+			i.lines = i.layout.paragraphLinesAt(i.paraIndex, para)
+			i.paraLineIndex = 0
+		}
+		i.layout.mu.Unlock()
 	}
 
 	return lineIndex, line
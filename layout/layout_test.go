@@ -0,0 +1,431 @@
+package layout
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/hyphen"
+	"github.com/rjw57/rwstar/surface"
+)
+
+func newTestLayout(t *testing.T, d *document.Document, width int) *Layout {
+	l, err := NewLayout(d, width)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func assertLayoutString(t *testing.T, l *Layout, lines ...string) {
+	s := l.String()
+	expected := strings.Join(lines, "\n") + "\n"
+	if s != expected {
+		t.Error("Layout not as expected.")
+		t.Errorf("Layout: %#v", s)
+		t.Errorf("Expected: %#v", expected)
+	}
+}
+
+func assertCellLocation(t *testing.T, l *Layout, p *document.Point, x, y int) {
+	px, py, err := l.CellLocationForPoint(p)
+	if err != nil {
+		t.Errorf("Point at offset %v: %v", p.TextOffset(), err)
+		return
+	}
+	if px != x || py != y {
+		t.Errorf("Point at offset %v: got (%v, %v), expected (%v, %v)", p.TextOffset(), px, py, x, y)
+	}
+}
+
+func alignedTestDocument(align document.Alignment) *document.Document {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("aa bb cc dd ee").Document()
+	return d.StartPoint().SetParagraphFormat(document.ParagraphFormat{Alignment: align}).Document()
+}
+
+func TestJustify(t *testing.T) {
+	l := newTestLayout(t, alignedTestDocument(document.AlignJustify), 10)
+	assertLayoutString(t, l, "aa  bb  cc", "dd ee¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(6), 8, 0)
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(12), 3, 1)
+}
+
+func TestAlignRight(t *testing.T) {
+	l := newTestLayout(t, alignedTestDocument(document.AlignRight), 10)
+	assertLayoutString(t, l, "  aa bb cc", "    dd ee¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(9), 4, 1)
+}
+
+func TestAlignCentre(t *testing.T) {
+	l := newTestLayout(t, alignedTestDocument(document.AlignCentre), 10)
+	assertLayoutString(t, l, " aa bb cc", "  dd ee¶")
+}
+
+func TestHyphenation(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("a hyphenation").Document()
+	d = d.SetHyphenation(document.HyphenationSettings{Language: "xx"})
+
+	l := newTestLayout(t, d, 8)
+	l.SetEmergencyBreaking(EmergencyBreaking{})
+	assertLayoutString(t, l, "a", "hyphenation¶")
+
+	l.SetHyphenationRegistry(hyphen.NewRegistry("testdata"))
+	assertLayoutString(t, l, "a hy-", "phen-", "ation¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(4), 0, 1)
+
+	d = d.SetHyphenation(document.HyphenationSettings{Language: "xx", Exceptions: []string{"hyphen-ation"}})
+	l.SetDocument(d)
+	assertLayoutString(t, l, "a", "hyphen-", "ation¶")
+}
+
+func TestSoftHyphen(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("a hy\u00adphen").Document()
+
+	l := newTestLayout(t, d, 20)
+	assertLayoutString(t, l, "a hyphen¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(5), 4, 0)
+
+	l.SetScreenWidth(6)
+	assertLayoutString(t, l, "a hy-", "phen¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(4), 4, 0)
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(5), 0, 1)
+}
+
+func TestEmergencyBreaking(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("see https://exämple.com/ ok").Document()
+
+	l := newTestLayout(t, d, 8)
+	assertLayoutString(t, l, "see", "https://", "exämple.", "com/ ok¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(12), 0, 2)
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(20), 0, 3)
+
+	l.SetEmergencyBreaking(EmergencyBreaking{Enabled: true, Marker: "\\"})
+	assertLayoutString(t, l, "see", "https:/\\", "/exämpl\\", "e.com/", "ok¶")
+
+	l.SetEmergencyBreaking(EmergencyBreaking{})
+	assertLayoutString(t, l, "see", "https://exämple.com/", "ok¶")
+}
+
+func TestTabs(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("\tc\tdd\tx.5\tend").Document()
+
+	l := newTestLayout(t, d, 40)
+	assertLayoutString(t, l, "        c       dd      x.5     end¶")
+
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{
+		TabStops: []document.TabStop{
+			{Column: 6, Kind: document.TabRight},
+			{Column: 10, Kind: document.TabCentre},
+			{Column: 14, Kind: document.TabDecimal},
+		},
+	}).Document()
+	l.SetDocument(d)
+	assertLayoutString(t, l, "     c   dd  x.5        end¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(6), 13, 0)
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(9), 16, 0)
+}
+
+func TestMargins(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("one two three four").Document()
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{
+		LeftMargin: 4, RightMargin: 15,
+	}).Document()
+
+	l := newTestLayout(t, d, 40)
+	assertLayoutString(t, l, "    one two\n    three four¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(8), 4, 1)
+}
+
+func TestHangingIndent(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("1.\tone two three four").Document()
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{
+		LeftIndent: 4, FirstLineIndent: -4, RightIndent: 25,
+	}).Document()
+
+	l := newTestLayout(t, d, 40)
+	assertLayoutString(t, l, "1.  one two\n    three four¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(3), 4, 0)
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(11), 4, 1)
+}
+
+func TestLineSpacing(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("one two three").End().InsertParagraphBreak().End().InsertText("four").Document()
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{
+		LineSpacing: document.LineSpacingDouble, SpaceBefore: 1,
+	}).Document()
+
+	l := newTestLayout(t, d, 8)
+	assertLayoutString(t, l, "\none two\n\nthree¶\n\nfour¶")
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(8), 0, 3)
+	assertCellLocation(t, l, l.Document().StartPoint().ForwardN(15), 1, 5)
+
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{
+		LineSpacing: document.LineSpacingOneAndAHalf, SpaceAfter: 2,
+	}).Document()
+	l.SetDocument(d)
+	assertLayoutString(t, l, "one two\nthree¶\n\n\n\nfour¶")
+}
+
+func assertPointForCell(t *testing.T, l *Layout, x, y, paraIdx, offset int) {
+	p := l.PointForCellLocation(x, y)
+	if p.ParagraphIndex() != paraIdx || p.TextOffset() != offset {
+		t.Errorf("Cell (%v, %v): got point (%v, %v), expected (%v, %v)",
+			x, y, p.ParagraphIndex(), p.TextOffset(), paraIdx, offset)
+	}
+}
+
+func TestPointForCellLocation(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("ab\t世界 cd").End().InsertParagraphBreak().End().InsertText("ef").Document()
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{SpaceAfter: 1}).Document()
+
+	l := newTestLayout(t, d, 40)
+	assertLayoutString(t, l, "ab      世界 cd¶", "", "ef¶")
+
+	assertPointForCell(t, l, 1, 0, 0, 1)
+	// Within the tab, snapping to the nearer side.
+	assertPointForCell(t, l, 4, 0, 0, 2)
+	assertPointForCell(t, l, 5, 0, 0, 3)
+	// Either half of a wide character.
+	assertPointForCell(t, l, 8, 0, 0, 3)
+	assertPointForCell(t, l, 9, 0, 0, 6)
+	// The pilcrow and beyond give the end of the paragraph.
+	assertPointForCell(t, l, 15, 0, 0, 12)
+	assertPointForCell(t, l, 30, 0, 0, 12)
+	// Blank lines snap to the nearest line of text.
+	assertPointForCell(t, l, 1, 1, 0, 1)
+	// Rows above and below the text.
+	assertPointForCell(t, l, 5, -1, 0, 0)
+	assertPointForCell(t, l, 0, 10, 1, 2)
+
+	// Points map back to the cells they were found from.
+	assertCellLocation(t, l, l.PointForCellLocation(9, 0), 10, 0)
+	assertCellLocation(t, l, l.PointForCellLocation(30, 0), 15, 0)
+}
+
+func TestLineIndexFollowsEdits(t *testing.T) {
+	d := document.NewDocument()
+	for i := 0; i < 20; i++ {
+		d = d.EndPoint().InsertText("one two three four five six").End().InsertParagraphBreak().Document()
+	}
+
+	l := newTestLayout(t, d, 10)
+	l.LineCount()
+
+	p := d.StartPoint().ForwardN(100)
+	for i := 0; i < 10; i++ {
+		p = p.InsertText("seven eight ").End()
+		if i%3 == 0 {
+			p = p.InsertParagraphBreak().End()
+		}
+		l.SetDocument(p.Document())
+		p = p.SetParagraphFormat(document.ParagraphFormat{SpaceAfter: i % 2})
+		l.SetDocument(p.Document())
+
+		fresh := newTestLayout(t, p.Document(), 10)
+		fresh.LineCount()
+		fresh.WaitForBackgroundLayout()
+		l.WaitForBackgroundLayout()
+		if l.LineCount() != fresh.LineCount() {
+			t.Fatalf("Line count: got %v, expected %v", l.LineCount(), fresh.LineCount())
+		}
+		for y := 0; y < fresh.LineCount(); y += 7 {
+			_, got := l.LineIterator(y).Next()
+			_, expected := fresh.LineIterator(y).Next()
+			if got.String() != expected.String() {
+				t.Fatalf("Line %v: got %#v, expected %#v", y, got.String(), expected.String())
+			}
+		}
+	}
+}
+
+func TestCacheSharedAcrossWidths(t *testing.T) {
+	d := document.NewDocument()
+	for i := 0; i < 3; i++ {
+		d = d.EndPoint().InsertText("the same paragraph of text").Document()
+	}
+
+	l := newTestLayout(t, d, 10)
+	l.SetBackgroundWorkers(0)
+	_ = l.String()
+
+	// Identical paragraphs share a single entry.
+	if s := l.CacheStats(); s.Misses != 1 || s.Hits != 2 {
+		t.Errorf("First layout: %+v", s)
+	}
+
+	// Switching width back and forth reuses earlier layouts.
+	l.SetScreenWidth(20)
+	_ = l.String()
+	l.SetScreenWidth(10)
+	_ = l.String()
+	if s := l.CacheStats(); s.Misses != 2 || s.Hits != 7 {
+		t.Errorf("After changing width: %+v", s)
+	}
+}
+
+func TestIncrementalLayout(t *testing.T) {
+	h, err := hyphen.NewRegistry("testdata").Get("xx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fragments := []string{
+		"a", "hyphenation", " ", "  ", "word", "\t", "\n", " ", "hy\u00adphen", "x",
+		"anoverlongwordwhichneedsbreaking", "é", "1.5", "(", ")", "-",
+	}
+
+	rng := rand.New(rand.NewSource(1))
+	for _, s := range []settings{
+		{screenWidth: 12},
+		{screenWidth: 12, emergencyBreaking: EmergencyBreaking{Enabled: true, Marker: "\\"}},
+		{screenWidth: 9, hyphenator: h},
+		{screenWidth: 15, breakStrategy: BreakStrategyTotalFit, hyphenator: h},
+	} {
+		d := document.NewDocument()
+		d = d.StartPoint().InsertText("some initial text which spans several lines").Document()
+		base := s.layoutParagraph(d.GetParagraph(0), nil)
+
+		for i := 0; i < 200; i++ {
+			p := d.GetParagraph(0)
+			p.ContentID()
+			text := p.String()
+			at := rng.Intn(len(text) + 1)
+			for at < len(text) && !utf8.RuneStart(text[at]) {
+				at--
+			}
+			d = d.PointAt(0, at).InsertText(fragments[rng.Intn(len(fragments))]).Document()
+
+			p = d.GetParagraph(0)
+			if _, ok := p.Origin(); !ok {
+				t.Fatal("Edited paragraph has no origin")
+			}
+			got := s.layoutParagraph(p, base)
+			expected := s.layoutParagraph(p, nil)
+			if !reflect.DeepEqual(got.items, expected.items) {
+				t.Fatalf("Items differ after inserting at %v in %q", at, p.String())
+			}
+			if !reflect.DeepEqual(got.lines, expected.lines) {
+				t.Fatalf("Lines differ after inserting at %v in %q", at, p.String())
+			}
+			base = got
+		}
+	}
+}
+
+// testMeasurer makes every character two units wide except spaces, which are one. Columns are two
+// units wide.
+type testMeasurer struct{}
+
+func (testMeasurer) TextWidth(text string, _ surface.Style) int {
+	w := 0
+	for _, r := range text {
+		w += 2
+		if r == ' ' {
+			w--
+		}
+	}
+	return w
+}
+
+func (testMeasurer) ColumnWidth() int {
+	return 2
+}
+
+func TestMeasurer(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("aa bb cc d").Document()
+
+	l := newTestLayout(t, d, 10)
+	assertLayoutString(t, l, "aa bb cc", "d¶")
+
+	// Narrow spaces let the text fit on one line.
+	l.SetMeasurer(testMeasurer{})
+	l.SetScreenWidth(20)
+	assertLayoutString(t, l, "aa bb cc d¶")
+
+	// Margins are given in columns.
+	d = d.StartPoint().SetParagraphFormat(document.ParagraphFormat{LeftMargin: 1}).Document()
+	l.SetDocument(d)
+	assertLayoutString(t, l, "  aa bb cc", "  d¶")
+}
+
+func TestViewOptions(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("aa bb\tcc­dd\nee").Document()
+
+	l := newTestLayout(t, d, 40)
+	assertLayoutString(t, l, "aa bb   ccdd", "ee¶")
+
+	l.SetViewOptions(ViewOptions{})
+	assertLayoutString(t, l, "aa bb   ccdd", "ee")
+
+	l.SetViewOptions(AllViewOptions())
+	assertLayoutString(t, l, "aa·bb→  cc-dd↵", "ee¶")
+
+	// Marks never reach the printer.
+	pl, err := NewPrintLayout(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if o := pl.ViewOptions(); o != (ViewOptions{}) {
+		t.Errorf("print layout view options: got %+v", o)
+	}
+}
+
+func TestHiddenMarksKeepEmptyParagraphs(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("ab").End().InsertParagraphBreak().End().
+		InsertParagraphBreak().End().InsertText("cd").Document()
+
+	l := newTestLayout(t, d, 40)
+	l.SetViewOptions(ViewOptions{})
+	assertLayoutString(t, l, "ab", "", "cd")
+
+	// The empty paragraph has a line on which the cursor is shown.
+	empty := d.PointAt(1, 0)
+	assertCellLocation(t, l, empty, 0, 1)
+	assertPointForCell(t, l, 5, 1, 1, 0)
+}
+
+func TestParseViewOptions(t *testing.T) {
+	o, err := ParseViewOptions("para, Tab,pagebreak")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := (ViewOptions{ParagraphMarks: true, Tabs: true, PageBreaks: true}); o != expected {
+		t.Errorf("Got %+v, expected %+v", o, expected)
+	}
+	if o, err := ParseViewOptions("all"); err != nil || o != AllViewOptions() {
+		t.Errorf("All marks: %+v, %v", o, err)
+	}
+	if _, err := ParseViewOptions("para,bogus"); err == nil {
+		t.Error("No error for unknown mark")
+	}
+}
+
+func TestLineBreakBoxesAreAtomic(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("ab\r\ncd").Document()
+
+	// A point between the carriage return and line feed is shown where the line break is.
+	l := newTestLayout(t, d, 40)
+	assertLayoutString(t, l, "ab", "cd¶")
+	assertCellLocation(t, l, d.PointAt(0, 3), 2, 0)
+
+	l.SetViewOptions(AllViewOptions())
+	assertLayoutString(t, l, "ab↵", "cd¶")
+	assertCellLocation(t, l, d.PointAt(0, 3), 2, 0)
+	assertPointForCell(t, l, 2, 0, 0, 2)
+	assertPointForCell(t, l, 10, 0, 0, 4)
+}
@@ -0,0 +1,219 @@
+package layout
+
+import "math/rand"
+
+// lineIndex is a persistent sequence of the number of lines in each paragraph of a document. It is
+// an implicit treap in which every node records the number of paragraphs and lines in its subtree
+// so that the paragraph containing a given line, the line at which a given paragraph starts and
+// the total number of lines may all be found in O(log n) time. Modifications return a new index
+// which shares unchanged nodes with the original. The zero value is an empty index.
+//
+// Line counts may be estimates for paragraphs which have not yet been laid out. The first such
+// paragraph after a given index can also be found in O(log n) time.
+type lineIndex struct {
+	root *lineIndexNode
+}
+
+// lineCount is the number of lines in a paragraph. If exact is false, it is an estimate.
+type lineCount struct {
+	lines int
+	exact bool
+}
+
+type lineIndexNode struct {
+	left, right *lineIndexNode
+	priority    uint32
+
+	// count is the number of lines in this node's paragraph.
+	count lineCount
+
+	// size, total and inexact are the number of paragraphs, lines and paragraphs with estimated
+	// line counts in the subtree rooted at this node.
+	size    int
+	total   int
+	inexact int
+}
+
+func (n *lineIndexNode) getSize() int {
+	if n == nil {
+		return 0
+	}
+	return n.size
+}
+
+func (n *lineIndexNode) getTotal() int {
+	if n == nil {
+		return 0
+	}
+	return n.total
+}
+
+func (n *lineIndexNode) getInexact() int {
+	if n == nil {
+		return 0
+	}
+	return n.inexact
+}
+
+// with returns a copy of n with new children and updated counts.
+func (n *lineIndexNode) with(left, right *lineIndexNode) *lineIndexNode {
+	nn := *n
+	nn.left, nn.right = left, right
+	nn.size = left.getSize() + 1 + right.getSize()
+	nn.total = left.getTotal() + n.count.lines + right.getTotal()
+	nn.inexact = left.getInexact() + right.getInexact()
+	if !n.count.exact {
+		nn.inexact++
+	}
+	return &nn
+}
+
+// mergeLineIndexNodes returns a tree holding the paragraphs of a followed by those of b.
+func mergeLineIndexNodes(a, b *lineIndexNode) *lineIndexNode {
+	switch {
+	case a == nil:
+		return b
+	case b == nil:
+		return a
+	case a.priority >= b.priority:
+		return a.with(a.left, mergeLineIndexNodes(a.right, b))
+	default:
+		return b.with(mergeLineIndexNodes(a, b.left), b.right)
+	}
+}
+
+// splitLineIndexNode returns trees holding the first k paragraphs of n and the remainder.
+func splitLineIndexNode(n *lineIndexNode, k int) (*lineIndexNode, *lineIndexNode) {
+	if n == nil {
+		return nil, nil
+	}
+	leftSize := n.left.getSize()
+	if k <= leftSize {
+		a, b := splitLineIndexNode(n.left, k)
+		return a, n.with(b, n.right)
+	}
+	a, b := splitLineIndexNode(n.right, k-leftSize-1)
+	return n.with(n.left, a), b
+}
+
+// buildLineIndexNodes returns a tree holding paragraphs with the given line counts.
+func buildLineIndexNodes(counts []lineCount) *lineIndexNode {
+	var root *lineIndexNode
+	for _, c := range counts {
+		n := (&lineIndexNode{priority: rand.Uint32(), count: c}).with(nil, nil)
+		root = mergeLineIndexNodes(root, n)
+	}
+	return root
+}
+
+// newLineIndex returns an index of paragraphs with the given line counts.
+func newLineIndex(counts []lineCount) lineIndex {
+	return lineIndex{root: buildLineIndexNodes(counts)}
+}
+
+// Len returns the number of paragraphs in the index.
+func (li lineIndex) Len() int {
+	return li.root.getSize()
+}
+
+// LineCount returns the total number of lines in all paragraphs.
+func (li lineIndex) LineCount() int {
+	return li.root.getTotal()
+}
+
+// Splice returns an index in which the paragraphs [start, end) are replaced by paragraphs with the
+// given line counts.
+func (li lineIndex) Splice(start, end int, counts []lineCount) lineIndex {
+	left, rest := splitLineIndexNode(li.root, start)
+	_, right := splitLineIndexNode(rest, end-start)
+	return lineIndex{
+		root: mergeLineIndexNodes(mergeLineIndexNodes(left, buildLineIndexNodes(counts)), right),
+	}
+}
+
+// LinesBefore returns the index of the first line of paragraph paraIdx, which is the total number
+// of lines in the paragraphs before it.
+func (li lineIndex) LinesBefore(paraIdx int) int {
+	lines := 0
+	for n := li.root; n != nil; {
+		if leftSize := n.left.getSize(); paraIdx <= leftSize {
+			if paraIdx == leftSize {
+				return lines + n.left.getTotal()
+			}
+			n = n.left
+		} else {
+			lines += n.left.getTotal() + n.count.lines
+			paraIdx -= leftSize + 1
+			n = n.right
+		}
+	}
+	return lines
+}
+
+// Find returns the index of the paragraph containing line lineIdx and the index of that
+// paragraph's first line. Returns false if the line is beyond the end of the index.
+func (li lineIndex) Find(lineIdx int) (paraIdx, firstLineIdx int, ok bool) {
+	if lineIdx < 0 || lineIdx >= li.LineCount() {
+		return -1, -1, false
+	}
+
+	for n := li.root; n != nil; {
+		leftTotal := n.left.getTotal()
+		switch {
+		case lineIdx < leftTotal:
+			n = n.left
+		case lineIdx < leftTotal+n.count.lines:
+			return paraIdx + n.left.getSize(), firstLineIdx + leftTotal, true
+		default:
+			lineIdx -= leftTotal + n.count.lines
+			firstLineIdx += leftTotal + n.count.lines
+			paraIdx += n.left.getSize() + 1
+			n = n.right
+		}
+	}
+
+	return -1, -1, false
+}
+
+// At returns the line count of paragraph paraIdx.
+func (li lineIndex) At(paraIdx int) lineCount {
+	for n := li.root; n != nil; {
+		leftSize := n.left.getSize()
+		switch {
+		case paraIdx < leftSize:
+			n = n.left
+		case paraIdx == leftSize:
+			return n.count
+		default:
+			paraIdx -= leftSize + 1
+			n = n.right
+		}
+	}
+	return lineCount{}
+}
+
+// FirstInexact returns the index of the first paragraph at or after paraIdx whose line count is an
+// estimate. Returns false if there is none.
+func (li lineIndex) FirstInexact(paraIdx int) (int, bool) {
+	return firstInexactNode(li.root, paraIdx)
+}
+
+func firstInexactNode(n *lineIndexNode, paraIdx int) (int, bool) {
+	if n.getInexact() == 0 {
+		return -1, false
+	}
+
+	leftSize := n.left.getSize()
+	if paraIdx < leftSize {
+		if i, ok := firstInexactNode(n.left, paraIdx); ok {
+			return i, true
+		}
+	}
+	if paraIdx <= leftSize && !n.count.exact {
+		return leftSize, true
+	}
+	if i, ok := firstInexactNode(n.right, paraIdx-leftSize-1); ok {
+		return leftSize + 1 + i, true
+	}
+	return -1, false
+}
@@ -0,0 +1,77 @@
+package layout
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// checkLineIndex compares the index with the line counts it should hold.
+func checkLineIndex(t *testing.T, li lineIndex, counts []lineCount) {
+	t.Helper()
+
+	if li.Len() != len(counts) {
+		t.Fatalf("Len: got %v, expected %v", li.Len(), len(counts))
+	}
+
+	first := 0
+	nextInexact := -1
+	for paraIdx := len(counts) - 1; paraIdx >= 0; paraIdx-- {
+		if !counts[paraIdx].exact {
+			nextInexact = paraIdx
+		}
+		if i, ok := li.FirstInexact(paraIdx); i != nextInexact || ok != (nextInexact >= 0) {
+			t.Fatalf("FirstInexact(%v): got (%v, %v), expected %v", paraIdx, i, ok, nextInexact)
+		}
+	}
+	for paraIdx, c := range counts {
+		if n := li.LinesBefore(paraIdx); n != first {
+			t.Fatalf("LinesBefore(%v): got %v, expected %v", paraIdx, n, first)
+		}
+		if got := li.At(paraIdx); got != c {
+			t.Fatalf("At(%v): got %+v, expected %+v", paraIdx, got, c)
+		}
+		for lineIdx := first; lineIdx < first+c.lines; lineIdx++ {
+			p, f, ok := li.Find(lineIdx)
+			if !ok || p != paraIdx || f != first {
+				t.Fatalf("Find(%v): got (%v, %v, %v), expected (%v, %v)", lineIdx, p, f, ok, paraIdx, first)
+			}
+		}
+		first += c.lines
+	}
+
+	if li.LineCount() != first {
+		t.Errorf("LineCount: got %v, expected %v", li.LineCount(), first)
+	}
+	if _, _, ok := li.Find(first); ok {
+		t.Errorf("Found line after end")
+	}
+}
+
+func TestLineIndex(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+
+	var counts []lineCount
+	var li lineIndex
+	checkLineIndex(t, li, counts)
+
+	for i := 0; i < 200; i++ {
+		start := r.Intn(len(counts) + 1)
+		end := start + r.Intn(len(counts)-start+1)
+		if end-start > 3 {
+			end = start + 3
+		}
+		inserted := make([]lineCount, r.Intn(4))
+		for j := range inserted {
+			inserted[j] = lineCount{lines: 1 + r.Intn(5), exact: r.Intn(3) > 0}
+		}
+
+		prev, prevCounts := li, append([]lineCount(nil), counts...)
+		li = li.Splice(start, end, inserted)
+		counts = append(append(append([]lineCount(nil), counts[:start]...), inserted...), counts[end:]...)
+
+		checkLineIndex(t, li, counts)
+
+		// The original index is unchanged.
+		checkLineIndex(t, prev, prevCounts)
+	}
+}
@@ -0,0 +1,110 @@
+package layout
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/surface"
+)
+
+// Measurer measures text for layout. Widths are in the units of the layout, which are also the
+// units of its screen width. A terminal layout measures in cells. A layout for print might measure
+// in fractions of a point using font metrics.
+type Measurer interface {
+	// TextWidth returns the natural width of text drawn in style.
+	TextWidth(text string, style surface.Style) int
+
+	// ColumnWidth returns the width of a column. The margins, indents and tab stops of paragraph
+	// formats are given in columns.
+	ColumnWidth() int
+}
+
+// CellMeasurer measures text in terminal cells. It is the default Measurer.
+type CellMeasurer struct{}
+
+func (CellMeasurer) TextWidth(text string, _ surface.Style) int {
+	return uniseg.StringWidth(text)
+}
+
+func (CellMeasurer) ColumnWidth() int {
+	return 1
+}
+
+// measurerRef refers to a Measurer from settings, which must be strictly comparable to be used as
+// part of a cache key.
+type measurerRef struct {
+	Measurer
+}
+
+// textMeasurer returns the measurer for the settings.
+func (s settings) textMeasurer() Measurer {
+	if s.measurer == nil {
+		return CellMeasurer{}
+	}
+	return s.measurer.Measurer
+}
+
+// setNaturalWidths measures each item with m.
+func setNaturalWidths(items []ParagraphItem, m Measurer) {
+	for i := range items {
+		item := &items[i]
+		item.NaturalWidth = m.TextWidth(item.Text, item.Style)
+
+		item.decimal = -1
+		if j := strings.IndexByte(item.Text, '.'); j >= 0 && item.Type == ParagraphItemTypeBox {
+			item.decimal = m.TextWidth(item.Text[:j], item.Style)
+		}
+	}
+}
+
+// scaleFormat returns f with its margins, indents and tab stops converted from columns to units
+// where a column is colWidth units wide. Default tab stops before width are made explicit since
+// they would otherwise be placed every DefaultTabInterval units.
+func scaleFormat(f document.ParagraphFormat, colWidth, width int) document.ParagraphFormat {
+	if colWidth == 1 {
+		return f
+	}
+
+	f.LeftMargin *= colWidth
+	f.RightMargin *= colWidth
+	f.LeftIndent *= colWidth
+	f.RightIndent *= colWidth
+	f.FirstLineIndent *= colWidth
+
+	stops := make([]document.TabStop, 0, len(f.TabStops)+width/(colWidth*document.DefaultTabInterval))
+	for _, ts := range f.TabStops {
+		ts.Column *= colWidth
+		stops = append(stops, ts)
+	}
+	last := -1
+	if n := len(f.TabStops); n > 0 {
+		last = f.TabStops[n-1].Column
+	}
+	for ts := f.NextTabStop(last); ts.Column*colWidth < width; ts = f.NextTabStop(ts.Column) {
+		stops = append(stops, document.TabStop{Column: ts.Column * colWidth})
+	}
+	f.TabStops = stops
+
+	return f
+}
+
+// SetMeasurer sets how text is measured. The screen width is in the units of the measurer.
+// Measurers are compared with ==, so m should be comparable, such as a pointer.
+func (l *Layout) SetMeasurer(m Measurer) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if m == l.settings.textMeasurer() {
+		return
+	}
+	l.invalidate()
+	l.settings.measurer = &measurerRef{m}
+}
+
+// Measurer returns how text is measured.
+func (l *Layout) Measurer() Measurer {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings.textMeasurer()
+}
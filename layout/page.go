@@ -0,0 +1,82 @@
+package layout
+
+import "github.com/rjw57/rwstar/document"
+
+// Page is a single printed page of a laid out document.
+type Page struct {
+	// Number is the page number as printed in headers and footers.
+	Number int
+
+	// Header is the header text for this page with page number fields expanded.
+	Header string
+
+	// Footer is the footer text for this page with page number fields expanded.
+	Footer string
+
+	// FirstLineIndex is the index within the layout of the first body line on this page.
+	FirstLineIndex int
+
+	// Lines are the body lines on this page.
+	Lines Lines
+}
+
+// Pages divides the layout into pages according to the document's page setup. The layout's screen
+// width is used as the width of the text; callers wanting printed output should create a layout
+// whose screen width matches the page setup.
+func (l *Layout) Pages() []Page {
+	ps := l.Document().PageSetup()
+	bodyLength := ps.BodyLength()
+
+	var pages []Page
+	newPage := func(firstLineIndex int) Page {
+		n := ps.FirstPageNumber + len(pages)
+		return Page{
+			Number:         n,
+			Header:         ps.Header.ForPage(n),
+			Footer:         ps.Footer.ForPage(n),
+			FirstLineIndex: firstLineIndex,
+		}
+	}
+
+	page := newPage(0)
+	for i := l.LineIterator(0); !i.Done(); {
+		lineIndex, ln := i.Next()
+		if len(page.Lines) >= bodyLength {
+			pages = append(pages, page)
+			page = newPage(lineIndex)
+		}
+		page.Lines = append(page.Lines, ln)
+	}
+	pages = append(pages, page)
+
+	return pages
+}
+
+// PageBreaks returns the point at the start of the text of each page after the first. Since points
+// do not depend on the width of a layout, they can be used to show where the pages of a print
+// layout break on the screen.
+func (l *Layout) PageBreaks() []*document.Point {
+	pages := l.Pages()
+	points := make([]*document.Point, 0, len(pages)-1)
+	for _, page := range pages[1:] {
+		// Skip blank lines left by spacing after the last paragraph of the previous page.
+		lineIdx := page.FirstLineIndex
+		for i, ln := range page.Lines {
+			if !ln.IsBlank() {
+				lineIdx += i
+				break
+			}
+		}
+		points = append(points, l.PointForCellLocation(-1, lineIdx))
+	}
+	return points
+}
+
+// PageIndexForPoint returns the index into Pages() of the page containing the passed point.
+func (l *Layout) PageIndexForPoint(p *document.Point) (int, error) {
+	_, y, err := l.CellLocationForPoint(p)
+	if err != nil {
+		return -1, err
+	}
+	return y / l.Document().PageSetup().BodyLength(), nil
+}
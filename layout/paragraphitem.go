@@ -1,8 +1,8 @@
 package layout
 
 import (
-	"github.com/gdamore/tcell/v2"
 	"github.com/rivo/uniseg"
+	"github.com/rjw57/rwstar/surface"
 )
 
 type ParagraphItemType int
@@ -11,30 +11,46 @@ const (
 	ParagraphItemTypeBox     ParagraphItemType = 0
 	ParagraphItemTypeGlue                      = iota
 	ParagraphItemTypePenalty                   = iota
+	ParagraphItemTypeTab                       = iota
 )
 
 type ParagraphItemPenalty int
 
 const (
-	ParagraphItemPenaltyNever   ParagraphItemType = 1000
-	ParagraphItemPenaltyNeutral                   = 0
-	ParagraphItemPenaltyAlways                    = -1000
+	ParagraphItemPenaltyNever     ParagraphItemPenalty = 1000
+	ParagraphItemPenaltyEmergency ParagraphItemPenalty = 500
+	ParagraphItemPenaltyHyphen    ParagraphItemPenalty = 50
+	ParagraphItemPenaltyNeutral   ParagraphItemPenalty = 0
+	ParagraphItemPenaltyAlways    ParagraphItemPenalty = -1000
 )
 
-// ParagraphItem represents a layout item within a paragraph. Items may be boxes, glue or penalties.
+// ParagraphItem represents a layout item within a paragraph. Items may be boxes, glue, penalties or
+// tabs.
 //
 // Boxes are literal horizontal collections of Cells to be rendered. Glue are Cells which are
 // rendered representing the space between words. Penalties represent explicit line-breaking
-// opportunity points.
+// opportunity points. Tabs are blank cells which advance to the next tab stop.
 type ParagraphItem struct {
-	// Type represents the type of this layout item: box, glue or penalty.
+	// Type represents the type of this layout item: box, glue, penalty or tab.
 	Type ParagraphItemType
 
-	// Text is the content of this item when rendered on screen. Only applicable to boxes.
+	// Text is the content of this item when rendered on screen. Glue has a single space as its
+	// natural content. Penalties with text are discretionary breaks: the text, usually a hyphen,
+	// is only rendered at the end of a line broken at the penalty.
 	Text string
 
-	// Style is the appearance of this item when rendered on screen.
-	Style tcell.Style
+	// Style is the appearance of this item when rendered on screen. Front ends usually take the
+	// colours from their theme according to whether the item is Markup and use only the
+	// attributes, such as bold, from Style.
+	Style surface.Style
+
+	// Markup is true for items which show formatting on screen, such as paragraph marks. Markup is
+	// never printed.
+	Markup bool
+
+	// Mark, if not empty, is shown in the first cell of glue or a tab to make it visible on
+	// screen. It is never printed. See ViewOptions.
+	Mark string
 
 	// StartOffset is the lowest inclusive offset within the underlying paragraph represented by
 	// this item.
@@ -44,16 +60,44 @@ type ParagraphItem struct {
 	// represented by this item.
 	EndOffset int
 
-	// Penalty gives a penalty for breaking the line at this item. If the penalty is +ve, the line
-	// will never be broken. If the penalty is 0, the line _may_ be broken. If the penalty is -ve
-	// the line will always be broken.
+	// NaturalWidth is the width of the item's text as measured by the layout's Measurer. For a
+	// penalty, it is the width of the text shown if the line is broken there.
+	NaturalWidth int
+
+	// Width is the width the item occupies once its line has been set, in the units of the
+	// layout's Measurer. Glue may be wider than its NaturalWidth when the line is justified. The
+	// width of a tab depends on its position within the line.
+	Width int
+
+	// Penalty gives a penalty for breaking the line at this item. If the penalty is at least
+	// ParagraphItemPenaltyNever, the line will never be broken here. If the penalty is at most
+	// ParagraphItemPenaltyAlways, the line will always be broken. Otherwise the line _may_ be
+	// broken; the total-fit strategy prefers breaks with lower penalties.
 	//
-	// Only glue and penalties can break lines. Penalty is ignored for boxes.
+	// Only glue and penalties can break lines. Penalty is ignored for boxes. Glue with a penalty of
+	// ParagraphItemPenaltyNever represents a no-break space.
 	Penalty ParagraphItemPenalty
+
+	// decimal is the natural width of the text before the first full stop in a box or -1 if
+	// there is none. It is used to align text on decimal tab stops.
+	decimal int
+}
+
+// IsDiscretionary returns true if the item is a penalty which renders text when the line is broken
+// there.
+func (p *ParagraphItem) IsDiscretionary() bool {
+	return p.Type == ParagraphItemTypePenalty && p.Text != ""
+}
+
+// showsSource returns true if the item is a box whose text is the text it represents, so that
+// positions within the text are positions within the paragraph. Boxes showing marks, such as a
+// line break hidden or drawn as "↵", must be treated as a whole.
+func (p *ParagraphItem) showsSource() bool {
+	return p.Type == ParagraphItemTypeBox && len(p.Text) == p.EndOffset-p.StartOffset
 }
 
 // CellCount is the *minimum* number of on-screen cells required to represent the item. Glue, in
-// particular, may be rendered with more cells.
+// particular, may be rendered with more cells. It is independent of the layout's Measurer.
 func (p *ParagraphItem) CellCount() int {
 	return uniseg.StringWidth(p.Text)
 }
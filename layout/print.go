@@ -0,0 +1,82 @@
+package layout
+
+import (
+	"bufio"
+	"io"
+	"strings"
+
+	"github.com/rjw57/rwstar/document"
+)
+
+// NewPrintLayout creates a layout for d whose width is the text width from the document's page
+// setup. No formatting marks are shown.
+func NewPrintLayout(d *document.Document) (*Layout, error) {
+	l, err := NewLayout(d, d.PageSetup().Width)
+	if err != nil {
+		return nil, err
+	}
+	l.SetViewOptions(ViewOptions{})
+	return l, nil
+}
+
+// lineText returns the printable text of a line. Markup items are omitted and trailing spaces are
+// trimmed.
+func lineText(ln Line) string {
+	var sb strings.Builder
+	sb.WriteString(strings.Repeat(" ", ln.Indent))
+	for _, item := range ln.Items {
+		switch {
+		case item.Markup:
+			// Markup is never printed.
+		case item.Type == ParagraphItemTypeBox:
+			sb.WriteString(item.Text)
+		case item.Type == ParagraphItemTypeGlue, item.Type == ParagraphItemTypeTab:
+			sb.WriteString(strings.Repeat(" ", item.Width))
+		}
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// PageText returns the lines of a page as a line printer would print them. There is one entry for
+// each line from the top of the page to the last non-blank line. Each line is indented by the page
+// offset.
+func (l *Layout) PageText(page Page) []string {
+	ps := l.Document().PageSetup()
+	offset := strings.Repeat(" ", ps.PageOffset)
+
+	text := make([]string, ps.Length)
+	if ln := ps.HeaderLine(); ln >= 0 && page.Header != "" {
+		text[ln] = offset + page.Header
+	}
+	for i, ln := range page.Lines {
+		if t := lineText(ln); t != "" && ps.TopMargin+i < len(text) {
+			text[ps.TopMargin+i] = offset + t
+		}
+	}
+	if ln := ps.FooterLine(); ln >= 0 && page.Footer != "" {
+		text[ln] = offset + page.Footer
+	}
+
+	for len(text) > 0 && text[len(text)-1] == "" {
+		text = text[:len(text)-1]
+	}
+	return text
+}
+
+// WritePlainText writes the layout to w as paginated plain text. Pages are separated by form
+// feeds. The output is suitable for sending directly to a line printer.
+func (l *Layout) WritePlainText(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+
+	for pageIdx, page := range l.Pages() {
+		if pageIdx > 0 {
+			bw.WriteRune('\f')
+		}
+		for _, t := range l.PageText(page) {
+			bw.WriteString(t)
+			bw.WriteRune('\n')
+		}
+	}
+
+	return bw.Flush()
+}
@@ -0,0 +1,88 @@
+package layout
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+)
+
+func TestWritePlainText(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("one two three four five six").Document()
+
+	ps := d.PageSetup()
+	ps.Width = 9
+	ps.Length = 5
+	ps.TopMargin = 2
+	ps.BottomMargin = 1
+	ps.HeaderMargin = 1
+	ps.FooterMargin = 0
+	ps.PageOffset = 1
+	ps.Header = document.RunningText{Odd: "odd #", Even: "even #"}
+	d = d.SetPageSetup(ps)
+
+	l, err := NewPrintLayout(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var sb strings.Builder
+	if err := l.WritePlainText(&sb); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := " odd 1\n\n one two\n three\n\f even 2\n\n four five\n six\n"
+	if sb.String() != expected {
+		t.Errorf("Output: %#v", sb.String())
+		t.Errorf("Expected: %#v", expected)
+	}
+}
+
+func TestPageBreaks(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("one two three four five six").Document()
+
+	ps := d.PageSetup()
+	ps.Width = 9
+	ps.Length = 2
+	ps.TopMargin = 0
+	ps.BottomMargin = 0
+	d = d.SetPageSetup(ps)
+
+	l, err := NewPrintLayout(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The lines are "one two", "three", "four" and "five six", two to a page.
+	points := l.PageBreaks()
+	if len(points) != 1 || points[0].TextOffset() != 14 {
+		for _, p := range points {
+			t.Errorf("Page break at %v", p.TextOffset())
+		}
+		t.Errorf("Expected a page break at 14")
+	}
+}
+
+func TestWritePlainTextShortPage(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("one").Document()
+	d = d.SetPageSetup(document.PageSetup{
+		Width: 9, Length: 1, TopMargin: 5, Header: document.NewRunningText("Title"),
+	})
+
+	l, err := NewPrintLayout(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The header and body text are below the end of the page and so are not printed.
+	var sb strings.Builder
+	if err := l.WritePlainText(&sb); err != nil {
+		t.Fatal(err)
+	}
+	if sb.String() != "" {
+		t.Errorf("Output: %#v", sb.String())
+	}
+}
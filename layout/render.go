@@ -1,18 +1,25 @@
 package layout
 
 import (
+	"sort"
+	"strings"
+	"unicode/utf8"
+
 	"github.com/rivo/uniseg"
 
 	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/hyphen"
 )
 
-func appendTextParagraphItems(items []ParagraphItem, text string, startOffset int) []ParagraphItem {
+// appendTextParagraphItems appends items representing text to items. If h is non-nil, words are
+// split at permitted hyphenation points by discretionary hyphens.
+func appendTextParagraphItems(items []ParagraphItem, text string, startOffset int, h *hyphen.Hyphenator) []ParagraphItem {
 	state := -1
 	var segment string
 
 	for len(text) > 0 {
 		segment, text, _, state = uniseg.FirstLineSegmentInString(text, state)
-		items = appendLineSegmentParagraphItems(items, segment, startOffset)
+		items = appendLineSegmentParagraphItems(items, segment, startOffset, h)
 		startOffset += len(segment)
 
 		// If the segment ends with a forced line break, add a penalty.
@@ -29,94 +36,458 @@ func appendTextParagraphItems(items []ParagraphItem, text string, startOffset in
 	return items
 }
 
-func appendLineSegmentParagraphItems(items []ParagraphItem, text string, startOffset int) []ParagraphItem {
+// Characters which prevent or allow line breaks.
+const (
+	runeNoBreakSpace          = '\u00a0'
+	runeNarrowNoBreakSpace    = '\u202f'
+	runeWordJoiner            = '\u2060'
+	runeZeroWidthNoBreakSpace = '\ufeff'
+	runeSoftHyphen            = '\u00ad'
+)
+
+// isBreakControl returns true for characters which are represented by glue or penalties rather
+// than boxes.
+func isBreakControl(r rune) bool {
+	switch r {
+	case ' ', '\t', runeNoBreakSpace, runeNarrowNoBreakSpace, runeWordJoiner, runeZeroWidthNoBreakSpace,
+		runeSoftHyphen:
+		return true
+	}
+	return false
+}
+
+func appendLineSegmentParagraphItems(items []ParagraphItem, text string, startOffset int, h *hyphen.Hyphenator) []ParagraphItem {
 	state := -1
 	var word string
 
 	for len(text) > 0 {
 		word, text, state = uniseg.FirstWordInString(text, state)
+		items = appendWordParagraphItems(items, word, startOffset, h)
+		startOffset += len(word)
+	}
 
-		// Multiple spaces become multiple glues
-		for len(word) > 0 && word[0] == ' ' {
-			item := ParagraphItem{
-				Type:        ParagraphItemTypeGlue,
-				StartOffset: startOffset,
-				EndOffset:   startOffset + 1,
-			}
-			items = append(items, item)
-			word = word[1:]
-			startOffset++
+	return items
+}
+
+func appendWordParagraphItems(items []ParagraphItem, word string, startOffset int, h *hyphen.Hyphenator) []ParagraphItem {
+	// Words with explicit soft hyphens are not hyphenated automatically.
+	if strings.ContainsRune(word, runeSoftHyphen) {
+		h = nil
+	}
+
+	for len(word) > 0 {
+		r, size := utf8.DecodeRuneInString(word)
+		item := ParagraphItem{
+			StartOffset: startOffset,
+			EndOffset:   startOffset + size,
 		}
 
-		if len(word) > 0 {
-			item := ParagraphItem{
-				Type:        ParagraphItemTypeBox,
-				Text:        word,
-				Style:       StyleNormal,
-				StartOffset: startOffset,
-				EndOffset:   startOffset + len(word),
+		switch r {
+		case ' ':
+			// Multiple spaces become multiple glues
+			item.Type = ParagraphItemTypeGlue
+			item.Text = " "
+		case '\t':
+			item.Type = ParagraphItemTypeTab
+		case runeNoBreakSpace, runeNarrowNoBreakSpace:
+			item.Type = ParagraphItemTypeGlue
+			item.Text = " "
+			item.Penalty = ParagraphItemPenaltyNever
+		case runeWordJoiner, runeZeroWidthNoBreakSpace:
+			item.Type = ParagraphItemTypePenalty
+			item.Penalty = ParagraphItemPenaltyNever
+		case runeSoftHyphen:
+			item.Type = ParagraphItemTypePenalty
+			item.Text = "-"
+			item.Penalty = ParagraphItemPenaltyHyphen
+		default:
+			if size = strings.IndexFunc(word, isBreakControl); size < 0 {
+				size = len(word)
 			}
-			items = append(items, item)
-			startOffset += len(word)
+			items = appendBoxParagraphItems(items, word[:size], startOffset, h)
+			word = word[size:]
+			startOffset += size
+			continue
 		}
+
+		items = append(items, item)
+		word = word[size:]
+		startOffset += size
 	}
 
 	return items
 }
 
-func (l *Layout) renderParagraphLines(p *document.Paragraph) Lines {
-	var lines Lines
-	var items []ParagraphItem
+// appendBoxParagraphItems appends a box for text, splitting it with discretionary hyphens at
+// permitted hyphenation points if h is non-nil.
+func appendBoxParagraphItems(items []ParagraphItem, text string, startOffset int, h *hyphen.Hyphenator) []ParagraphItem {
+	var points []int
+	if h != nil {
+		points = h.Hyphenate(text)
+	}
 
-	text := p.String()
-	items = appendTextParagraphItems(items, text, 0)
+	prev := 0
+	for _, point := range append(points, len(text)) {
+		items = append(items, ParagraphItem{
+			Type:        ParagraphItemTypeBox,
+			Text:        text[prev:point],
+			StartOffset: startOffset + prev,
+			EndOffset:   startOffset + point,
+		})
+		if point < len(text) {
+			items = append(items, ParagraphItem{
+				Type:        ParagraphItemTypePenalty,
+				Text:        "-",
+				StartOffset: startOffset + point,
+				EndOffset:   startOffset + point,
+				Penalty:     ParagraphItemPenaltyHyphen,
+			})
+		}
+		prev = point
+	}
 
-	// add forced line break
-	items = append(items, []ParagraphItem{{
+	return items
+}
+
+// paragraphLayout is a laid out paragraph along with the intermediate results needed to lay out
+// an edited copy of it incrementally. None of its slices are modified once it has been made.
+type paragraphLayout struct {
+	// items represent the paragraph text before any end of paragraph marker is added.
+	items []ParagraphItem
+
+	// setItems are the items which were broken into lines. Their widths have been set.
+	setItems []ParagraphItem
+
+	// breaks are the indices of the items in setItems at which lines were broken.
+	breaks []int
+
+	// textLines are the lines of text before blank lines were added for spacing.
+	textLines Lines
+
+	// lines are the lines of the paragraph.
+	lines Lines
+}
+
+// paragraphItems returns the items representing the text of p. If base is the layout of the
+// paragraph p was derived from, only the text around the edit is segmented again.
+func (s settings) paragraphItems(p *document.Paragraph, base *paragraphLayout) []ParagraphItem {
+	o, ok := p.Origin()
+	if !ok || base == nil {
+		return appendTextParagraphItems(nil, p.String(), 0, s.hyphenator)
+	}
+	items := base.items
+
+	// Segment again from the last space before the edit to the first space after it. Text outside
+	// that range is separated from the edit by a line break opportunity and so is unaffected.
+	first := sort.Search(len(items), func(i int) bool { return items[i].EndOffset > o.Start })
+	for first--; first >= 0 && !isSpace(items[first]); first-- {
+	}
+	segStart := 0
+	if first < 0 {
+		first = 0
+	} else {
+		segStart = items[first].StartOffset
+	}
+
+	last := sort.Search(len(items), func(i int) bool { return items[i].StartOffset >= o.End })
+	for ; last < len(items) && !isSpace(items[last]); last++ {
+	}
+	delta := o.Inserted - (o.End - o.Start)
+	segEnd := p.TextLength()
+	if last < len(items) {
+		segEnd = items[last].EndOffset + delta
+		last++
+	}
+
+	edited := make([]ParagraphItem, 0, len(items)+o.Inserted)
+	edited = append(edited, items[:first]...)
+	edited = appendTextParagraphItems(edited, p.TextSlice(segStart, segEnd), segStart, s.hyphenator)
+	for _, item := range items[last:] {
+		item.StartOffset += delta
+		item.EndOffset += delta
+		edited = append(edited, item)
+	}
+	return edited
+}
+
+// isSpace returns true if item is glue at which a line may be broken.
+func isSpace(item ParagraphItem) bool {
+	return item.Type == ParagraphItemTypeGlue && item.Penalty != ParagraphItemPenaltyNever
+}
+
+// reusableLines returns the number of leading lines of base which may be reused when breaking
+// items greedily, along with the index of the first item following them. A greedy break depends
+// only on the items up to the break which ends the following line, so a line may be reused if that
+// break comes before the first item which differs from base. Lines are not reused if base has an
+// emergency break before the difference since those breaks depend on where earlier lines ended.
+func reusableLines(base *paragraphLayout, items []ParagraphItem) (int, int) {
+	diff := 0
+	for diff < len(items) && diff < len(base.setItems) {
+		a, b := items[diff], base.setItems[diff]
+		b.Width = a.Width
+		if a != b {
+			break
+		}
+		if isEmergencyBreak(a) {
+			return 0, 0
+		}
+		diff++
+	}
+
+	n := 0
+	for n+1 < len(base.breaks) && base.breaks[n+1] < diff {
+		n++
+	}
+	if n == 0 {
+		return 0, 0
+	}
+	return n, base.breaks[n-1] + 1
+}
+
+// layoutParagraph lays out p. If base is the layout of the paragraph p was derived from, it is
+// used to avoid segmenting all of the text again and, when breaking greedily, to reuse lines
+// before the edit.
+func (s settings) layoutParagraph(p *document.Paragraph, base *paragraphLayout) *paragraphLayout {
+	pl := &paragraphLayout{items: s.paragraphItems(p, base)}
+
+	// Copy the items so that adding marks and setting widths leaves pl.items untouched.
+	textLength := p.TextLength()
+	items := make([]ParagraphItem, 0, len(pl.items)+2)
+	items = s.view.appendMarkedItems(items, pl.items)
+
+	// The paragraph mark is always added, with no text if it is hidden, so that even an empty
+	// paragraph has a line on which the cursor can be shown.
+	mark := ParagraphItem{
 		Type:        ParagraphItemTypeBox,
-		Text:        "¶",
-		Style:       StyleMarkup,
-		StartOffset: len(text),
-		EndOffset:   len(text),
-	}, {
+		Markup:      true,
+		StartOffset: textLength,
+		EndOffset:   textLength,
+	}
+	if s.view.ParagraphMarks {
+		mark.Text = markParagraph
+	}
+	items = append(items, mark)
+
+	// add forced line break
+	items = append(items, ParagraphItem{
 		Type:        ParagraphItemTypePenalty,
-		StartOffset: len(text),
-		EndOffset:   len(text),
+		StartOffset: textLength,
+		EndOffset:   textLength,
 		Penalty:     ParagraphItemPenaltyAlways,
-	}}...)
+	})
+
+	m := s.textMeasurer()
+	format := scaleFormat(p.Format(), m.ColumnWidth(), s.screenWidth)
+	align := format.Alignment
+	shape := newLineShape(format, s.screenWidth)
+
+	// With a hanging indent, a tab on the first line advances to the indent of the other lines.
+	if shape.firstStart < shape.start {
+		format = format.WithTabStop(document.TabStop{Column: shape.start})
+	}
+
+	if s.emergencyBreaking.Enabled {
+		items = splitOverlongBoxes(items, shape.minWidth(), s.emergencyBreaking.Marker, m)
+	}
+	setNaturalWidths(items, m)
 
 	if items[len(items)-1].Penalty != ParagraphItemPenaltyAlways || items[len(items)-1].Type != ParagraphItemTypePenalty {
 		panic("Paragraph items do not end in forced break.")
 	}
 
-	// array giving running width of boxes up to but not including item at that index
-	runningWidths := make([]int, 1, len(items)+1)
-	for _, item := range items {
-		runningWidths = append(runningWidths, runningWidths[len(runningWidths)-1]+item.CellCount())
-	}
+	measure := newLineMeasure(items, shape, format)
 
+	var breaks []int
 	lineStartIdx := 0
-	lineBreakIdx := -1
-	for itemIdx, item := range items {
-		// We can never break on boxes
-		if item.Type == ParagraphItemTypeBox {
+	switch s.breakStrategy {
+	case BreakStrategyTotalFit:
+		breaks = breakTotalFit(items, measure, shape, align == document.AlignJustify)
+	default:
+		if base != nil {
+			var reused int
+			reused, lineStartIdx = reusableLines(base, items)
+			pl.breaks = base.breaks[:reused:reused]
+			pl.textLines = base.textLines[:reused:reused]
+		}
+		breaks = breakGreedyFrom(items, measure, shape, lineStartIdx)
+	}
+
+	for _, itemIdx := range breaks {
+		// Limit capacity so that appending a hyphen copies rather than overwriting the break.
+		lineItems := items[lineStartIdx:itemIdx:itemIdx]
+		if breakItem := items[itemIdx]; breakItem.IsDiscretionary() {
+			lineItems = append(lineItems, ParagraphItem{
+				Type:         ParagraphItemTypeBox,
+				Text:         breakItem.Text,
+				Style:        breakItem.Style,
+				Markup:       breakItem.Markup,
+				StartOffset:  breakItem.StartOffset,
+				EndOffset:    breakItem.EndOffset,
+				NaturalWidth: breakItem.NaturalWidth,
+				decimal:      -1,
+			})
+		}
+
+		// Lines ended by a forced break, such as the last line of a paragraph, are not justified.
+		justify := align == document.AlignJustify && !isForcedBreak(items[itemIdx])
+		start, lineWidth := shape.forLine(lineStartIdx)
+		pl.textLines = append(pl.textLines, setLine(lineItems, start, lineWidth, format, justify))
+		lineStartIdx = itemIdx + 1
+	}
+
+	pl.setItems = items
+	pl.breaks = append(pl.breaks, breaks...)
+	pl.lines = spaceLines(pl.textLines, format)
+
+	key := cacheKey{content: p.ContentID(), settings: s}
+	for i := range pl.lines {
+		pl.lines[i].ID = LineID{key: key, index: i}
+	}
+	return pl
+}
+
+// spaceLines returns lines with blank lines added according to the line spacing and paragraph
+// spacing of the format. Blank lines have no items.
+func spaceLines(lines Lines, f document.ParagraphFormat) Lines {
+	if f.LineSpacing == document.LineSpacingSingle && f.SpaceBefore <= 0 && f.SpaceAfter <= 0 {
+		return lines
+	}
+
+	spaced := make(Lines, 0, 2*len(lines)+f.SpaceBefore+f.SpaceAfter)
+	for i := 0; i < f.SpaceBefore; i++ {
+		spaced = append(spaced, Line{})
+	}
+	for n, ln := range lines {
+		spaced = append(spaced, ln)
+		for i := 0; i < f.LineSpacing.BlankLinesAfter(n); i++ {
+			spaced = append(spaced, Line{})
+		}
+	}
+	for i := 0; i < f.SpaceAfter; i++ {
+		spaced = append(spaced, Line{})
+	}
+	return spaced
+}
+
+// splitOverlongBoxes finds runs of boxes with no permitted break between them which are wider than
+// lineWidth when measured with m. The boxes in such runs are split between each grapheme cluster so that they may be
+// broken across lines. The breaks are discretionary with marker as their text.
+func splitOverlongBoxes(items []ParagraphItem, lineWidth int, marker string, m Measurer) []ParagraphItem {
+	var split []ParagraphItem
+
+	for runStart := 0; runStart < len(items); {
+		// Find the run of items starting here which may not be broken.
+		runEnd, runWidth := runStart, 0
+		for runEnd < len(items) && isProhibitedBreak(items[runEnd]) {
+			if items[runEnd].Type != ParagraphItemTypePenalty {
+				runWidth += m.TextWidth(items[runEnd].Text, items[runEnd].Style)
+			}
+			runEnd++
+		}
+		if runEnd == runStart {
+			runEnd++
+		}
+
+		if runWidth <= lineWidth {
+			if split != nil {
+				split = append(split, items[runStart:runEnd]...)
+			}
+			runStart = runEnd
 			continue
 		}
 
-		// Compute width of a line from current start breaking here.
-		w := runningWidths[itemIdx] - runningWidths[lineStartIdx]
+		if split == nil {
+			split = append(make([]ParagraphItem, 0, len(items)), items[:runStart]...)
+		}
 
-		// If this is feasible, record it.
-		if w <= l.screenWidth {
-			lineBreakIdx = itemIdx
+		emergencyBreak := func(offset int) ParagraphItem {
+			return ParagraphItem{
+				Type:        ParagraphItemTypePenalty,
+				Text:        marker,
+				Markup:      true,
+				StartOffset: offset,
+				EndOffset:   offset,
+				Penalty:     ParagraphItemPenaltyEmergency,
+			}
 		}
 
-		// If not feasible or is forced, record line.
-		if w > l.screenWidth || item.Penalty < 0 {
-			lines = append(lines, items[lineStartIdx:lineBreakIdx])
-			lineStartIdx = lineBreakIdx + 1
+		for idx, item := range items[runStart:runEnd] {
+			if item.Type != ParagraphItemTypeBox {
+				split = append(split, item)
+				continue
+			}
+
+			// Allow a break between adjacent boxes.
+			if idx > 0 && split[len(split)-1].Type == ParagraphItemTypeBox {
+				split = append(split, emergencyBreak(item.StartOffset))
+			}
+
+			state := -1
+			text := item.Text
+			offset := item.StartOffset
+			for len(text) > 0 {
+				var cluster string
+				cluster, text, _, state = uniseg.FirstGraphemeClusterInString(text, state)
+
+				box := item
+				box.Text = cluster
+				box.StartOffset = offset
+				box.EndOffset = offset + len(cluster)
+				split = append(split, box)
+				offset += len(cluster)
+
+				if len(text) > 0 {
+					split = append(split, emergencyBreak(offset))
+				}
+			}
+		}
+
+		runStart = runEnd
+	}
+
+	if split == nil {
+		return items
+	}
+	return split
+}
+
+// setLine sets the Width of each item and positions the line within lineWidth cells starting at
+// column start according to the paragraph format. If justify is true, extra cells are distributed
+// between the glue items after the last tab so that the line fills its width.
+func setLine(items []ParagraphItem, start, lineWidth int, f document.ParagraphFormat, justify bool) Line {
+	w := measureItems(items, start, f, true)
+
+	var glueIdxs []int
+	for i := range items {
+		switch items[i].Type {
+		case ParagraphItemTypeGlue:
+			glueIdxs = append(glueIdxs, i)
+		case ParagraphItemTypeTab:
+			glueIdxs = glueIdxs[:0]
+		}
+	}
+
+	slack := lineWidth - w
+	if slack <= 0 {
+		return Line{Items: items, Indent: start}
+	}
+
+	switch f.Alignment {
+	case document.AlignRight:
+		return Line{Items: items, Indent: start + slack}
+	case document.AlignCentre:
+		return Line{Items: items, Indent: start + slack/2}
+	case document.AlignJustify:
+		if !justify || len(glueIdxs) == 0 {
+			break
+		}
+		for n, i := range glueIdxs {
+			items[i].Width += slack / len(glueIdxs)
+			if n < slack%len(glueIdxs) {
+				items[i].Width++
+			}
 		}
 	}
 
-	return lines
+	return Line{Items: items, Indent: start}
 }
@@ -0,0 +1,101 @@
+package layout
+
+import "github.com/rjw57/rwstar/document"
+
+// naturalWidth returns the width of an item within a line before tabs are resolved or glue is
+// stretched. Penalties within a line take up no space.
+func naturalWidth(item ParagraphItem) int {
+	if item.Type == ParagraphItemTypePenalty {
+		return 0
+	}
+	return item.NaturalWidth
+}
+
+// tabWidth returns the width of a tab at column x given the items which follow it on the line.
+// Tabs are always at least one cell wide.
+func tabWidth(following []ParagraphItem, x int, f document.ParagraphFormat) int {
+	ts := f.NextTabStop(x)
+
+	// Find the width of the text up to the next tab and up to its first decimal point.
+	segment, decimal := 0, -1
+	for _, item := range following {
+		if item.Type == ParagraphItemTypeTab {
+			break
+		}
+		if item.Markup {
+			continue
+		}
+		if decimal < 0 && item.decimal >= 0 {
+			decimal = segment + item.decimal
+		}
+		segment += naturalWidth(item)
+	}
+	if decimal < 0 {
+		decimal = segment
+	}
+
+	w := ts.Column - x
+	switch ts.Kind {
+	case document.TabRight:
+		w -= segment
+	case document.TabCentre:
+		w -= segment / 2
+	case document.TabDecimal:
+		w -= decimal
+	}
+	if w < 1 {
+		return 1
+	}
+	return w
+}
+
+// measureItems returns the width of a line made up of items starting at column x with tabs
+// resolved against the tab stops in f. If setWidths is true, each item's Width is set to its
+// natural or resolved width.
+func measureItems(items []ParagraphItem, x int, f document.ParagraphFormat, setWidths bool) int {
+	start := x
+	for i := range items {
+		w := naturalWidth(items[i])
+		if items[i].Type == ParagraphItemTypeTab {
+			w = tabWidth(items[i+1:], x, f)
+		}
+		if setWidths {
+			items[i].Width = w
+		}
+		x += w
+	}
+	return x - start
+}
+
+// lineMeasure returns the width of a line made up of items[startIdx:breakIdx] when broken at
+// breakIdx.
+type lineMeasure func(startIdx, breakIdx int) int
+
+// newLineMeasure returns a lineMeasure for items in lines with the given shape. If there are no
+// tabs, widths are additive and a running sum is used. Otherwise each line is measured by resolving
+// its tabs.
+func newLineMeasure(items []ParagraphItem, shape lineShape, f document.ParagraphFormat) lineMeasure {
+	hasTabs := false
+	for _, item := range items {
+		if item.Type == ParagraphItemTypeTab {
+			hasTabs = true
+			break
+		}
+	}
+
+	if !hasTabs {
+		widths := runningWidths(items)
+		return func(startIdx, breakIdx int) int {
+			return breakWidth(items, widths, startIdx, breakIdx)
+		}
+	}
+
+	return func(startIdx, breakIdx int) int {
+		start, _ := shape.forLine(startIdx)
+		w := measureItems(items[startIdx:breakIdx], start, f, false)
+		if items[breakIdx].Type == ParagraphItemTypePenalty {
+			w += items[breakIdx].NaturalWidth
+		}
+		return w
+	}
+}
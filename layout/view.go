@@ -0,0 +1,142 @@
+package layout
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ViewOptions control which formatting marks a layout shows. Marks are markup and are never
+// printed.
+type ViewOptions struct {
+	// ParagraphMarks shows "¶" at the end of each paragraph.
+	ParagraphMarks bool
+
+	// Spaces shows "·" in the first cell of each space.
+	Spaces bool
+
+	// Tabs shows "→" in the first cell of each tab.
+	Tabs bool
+
+	// SoftHyphens shows soft hyphens wherever they are, not only where a line is broken at one.
+	SoftHyphens bool
+
+	// LineBreaks shows "↵" at forced line breaks within a paragraph.
+	LineBreaks bool
+
+	// PageBreaks asks front ends to mark where printed pages break. See Layout.PageBreaks.
+	PageBreaks bool
+}
+
+// Marks shown by layouts.
+const (
+	markParagraph = "¶"
+	markSpace     = "·"
+	markTab       = "→"
+	markLineBreak = "↵"
+	markHyphen    = "-"
+)
+
+// DefaultViewOptions returns the options of a new layout, which shows paragraph marks only.
+func DefaultViewOptions() ViewOptions {
+	return ViewOptions{ParagraphMarks: true}
+}
+
+// AllViewOptions returns options which show every mark.
+func AllViewOptions() ViewOptions {
+	return ViewOptions{
+		ParagraphMarks: true,
+		Spaces:         true,
+		Tabs:           true,
+		SoftHyphens:    true,
+		LineBreaks:     true,
+		PageBreaks:     true,
+	}
+}
+
+// ParseViewOptions parses a comma separated list of the marks to show. The names are para, space,
+// tab, hyphen, linebreak and pagebreak. The name all shows every mark and none shows none.
+func ParseViewOptions(s string) (ViewOptions, error) {
+	var o ViewOptions
+	for _, name := range strings.Split(s, ",") {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "para":
+			o.ParagraphMarks = true
+		case "space":
+			o.Spaces = true
+		case "tab":
+			o.Tabs = true
+		case "hyphen":
+			o.SoftHyphens = true
+		case "linebreak":
+			o.LineBreaks = true
+		case "pagebreak":
+			o.PageBreaks = true
+		case "all":
+			o = AllViewOptions()
+		case "none":
+		default:
+			return ViewOptions{}, fmt.Errorf("layout: unknown formatting mark %q", name)
+		}
+	}
+	return o, nil
+}
+
+// appendMarkedItems appends items to dst with the marks shown by the options added. Forced line
+// breaks are drawn only as marks.
+func (o ViewOptions) appendMarkedItems(dst, items []ParagraphItem) []ParagraphItem {
+	for _, item := range items {
+		switch {
+		case o.Spaces && item.Type == ParagraphItemTypeGlue:
+			item.Mark = markSpace
+		case o.Tabs && item.Type == ParagraphItemTypeTab:
+			item.Mark = markTab
+		case item.Type == ParagraphItemTypeBox && isNewline(item.Text):
+			// The line break itself is never drawn.
+			item.Text = ""
+			if o.LineBreaks {
+				item.Text, item.Markup = markLineBreak, true
+			}
+		case o.SoftHyphens && isSoftHyphen(item):
+			// The hyphen is always shown so the break need not add one.
+			dst = append(dst, ParagraphItem{
+				Type:        ParagraphItemTypeBox,
+				Text:        markHyphen,
+				Markup:      true,
+				StartOffset: item.StartOffset,
+				EndOffset:   item.EndOffset,
+			})
+			item.Text = ""
+		}
+		dst = append(dst, item)
+	}
+	return dst
+}
+
+// isNewline returns true if text consists only of line break characters.
+func isNewline(text string) bool {
+	return text != "" && strings.Trim(text, "\r\n\v\f\u0085") == ""
+}
+
+// isSoftHyphen returns true if item represents a soft hyphen in the text, as opposed to a
+// hyphenation point found automatically.
+func isSoftHyphen(item ParagraphItem) bool {
+	return item.IsDiscretionary() && item.Penalty == ParagraphItemPenaltyHyphen && item.EndOffset > item.StartOffset
+}
+
+// ViewOptions returns which formatting marks the layout shows.
+func (l *Layout) ViewOptions() ViewOptions {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.settings.view
+}
+
+// SetViewOptions sets which formatting marks the layout shows.
+func (l *Layout) SetViewOptions(o ViewOptions) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if o == l.settings.view {
+		return
+	}
+	l.invalidate()
+	l.settings.view = o
+}
@@ -0,0 +1,70 @@
+package layout
+
+// Viewport is a window onto the lines of a layout which scrolls to follow the cursor.
+type Viewport struct {
+	// Top is the index of the first visible line.
+	Top int
+
+	// Height is the number of visible lines.
+	Height int
+
+	// ScrollMargin is the number of lines which are kept visible above and below the cursor where
+	// possible.
+	ScrollMargin int
+}
+
+// margin returns the scroll margin limited so that there is always at least one row on which the
+// cursor may be placed.
+func (v Viewport) margin() int {
+	m := v.ScrollMargin
+	if 2*m >= v.Height {
+		m = (v.Height - 1) / 2
+	}
+	if m < 0 {
+		return 0
+	}
+	return m
+}
+
+// Follow scrolls so that line y is visible and, where possible, at least ScrollMargin lines from
+// the top and bottom of the viewport.
+func (v *Viewport) Follow(y int) {
+	m := v.margin()
+	if y+m >= v.Top+v.Height {
+		v.Top = y + m - v.Height + 1
+	}
+	if y-m < v.Top {
+		v.Top = y - m
+	}
+	if v.Top < 0 {
+		v.Top = 0
+	}
+}
+
+// Scroll moves the viewport down by n lines, or up if n is negative. The viewport is kept within
+// a layout of lineCount lines.
+func (v *Viewport) Scroll(n, lineCount int) {
+	v.Top += n
+	if v.Top > lineCount-1 {
+		v.Top = lineCount - 1
+	}
+	if v.Top < 0 {
+		v.Top = 0
+	}
+}
+
+// Clamp returns the line nearest to y for which Follow does not scroll.
+func (v Viewport) Clamp(y int) int {
+	m := v.margin()
+	lo, hi := v.Top+m, v.Top+v.Height-1-m
+	if v.Top == 0 {
+		lo = 0
+	}
+	switch {
+	case y < lo:
+		return lo
+	case y > hi:
+		return hi
+	}
+	return y
+}
@@ -0,0 +1,53 @@
+package layout
+
+import "testing"
+
+func TestViewportFollow(t *testing.T) {
+	v := Viewport{Height: 10, ScrollMargin: 2}
+
+	v.Follow(5)
+	if v.Top != 0 {
+		t.Errorf("Scrolled for visible line: top %v", v.Top)
+	}
+	v.Follow(8)
+	if v.Top != 1 {
+		t.Errorf("Scrolling down: top %v", v.Top)
+	}
+	v.Follow(20)
+	if v.Top != 13 {
+		t.Errorf("Jumping down: top %v", v.Top)
+	}
+	v.Follow(14)
+	if v.Top != 12 {
+		t.Errorf("Scrolling up: top %v", v.Top)
+	}
+	v.Follow(1)
+	if v.Top != 0 {
+		t.Errorf("Scrolling to start: top %v", v.Top)
+	}
+}
+
+func TestViewportScrollAndClamp(t *testing.T) {
+	v := Viewport{Height: 10, ScrollMargin: 2}
+
+	v.Scroll(-1, 100)
+	if v.Top != 0 {
+		t.Errorf("Scrolled above start: top %v", v.Top)
+	}
+	if y := v.Clamp(0); y != 0 {
+		t.Errorf("Clamp at start: %v", y)
+	}
+
+	v.Scroll(3, 100)
+	if y := v.Clamp(0); y != 5 {
+		t.Errorf("Clamp above: %v", y)
+	}
+	if y := v.Clamp(20); y != 10 {
+		t.Errorf("Clamp below: %v", y)
+	}
+
+	v.Scroll(200, 100)
+	if v.Top != 99 {
+		t.Errorf("Scrolled past end: top %v", v.Top)
+	}
+}
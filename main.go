@@ -1,113 +1,440 @@
 package main
 
 import (
+	"flag"
+	"fmt"
+	"io"
 	"log"
+	"math"
 	"os"
+	"strings"
+	"unicode"
 
 	"github.com/gdamore/tcell/v2"
-	"github.com/rivo/uniseg"
 	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/hyphen"
 	"github.com/rjw57/rwstar/layout"
+	"github.com/rjw57/rwstar/pdf"
+	"github.com/rjw57/rwstar/surface"
+	"github.com/rjw57/rwstar/surface/tcellsurface"
+	"github.com/rjw57/rwstar/theme"
 )
 
-var rulerStyle = tcell.StyleDefault
+var (
+	patternsDir = flag.String("patterns", "", "load hyphenation patterns from `dir`")
+	language    = flag.String("lang", "", "hyphenate the document for `language`, e.g. en-gb")
 
-/*
-func drawRuler(s tcell.Screen, y int, m Margins) {
+	scrollMargin = flag.Int("scrollmargin", 2, "keep `n` lines visible above and below the cursor")
+
+	themeName = flag.String("theme", "", "use the built-in theme or theme file `name`")
+
+	marks = flag.String("marks", "all",
+		"formatting marks `list` shown by ^OD: para, space, tab, hyphen, linebreak, pagebreak or all")
+
+	// shownMarks are the marks shown by ^OD, parsed from the marks flag.
+	shownMarks layout.ViewOptions
+
+	// otherMarks are the marks ^OD returns to from shownMarks.
+	otherMarks = layout.DefaultViewOptions()
+
+	dotFile = flag.String("dot", "", "read page setup dot commands, such as .he and .fo, from `file`")
+
+	// dotCommands are the dot commands read from dotFile.
+	dotCommands string
+
+	// currentTheme gives the styles used to draw the screen.
+	currentTheme = theme.Default()
+
+	// hyphenRegistry loads hyphenation patterns from patternsDir. It is nil if no directory was
+	// given.
+	hyphenRegistry *hyphen.Registry
+)
+
+// Line drawing characters.
+const (
+	runeBullet   = '·'
+	runeVLine    = '│'
+	runeHLine    = '─'
+	runeLRCorner = '┘'
+)
+
+// tabStopRunes are the characters used to mark each kind of tab stop on the ruler.
+var tabStopRunes = map[document.TabKind]rune{
+	document.TabLeft:    '|',
+	document.TabRight:   '>',
+	document.TabCentre:  '^',
+	document.TabDecimal: '#',
+}
+
+// loadTheme sets the current theme from the -theme flag or, if it is not given, from the user's
+// theme file if there is one.
+func loadTheme() error {
+	name := *themeName
+	if name == "" {
+		file, err := theme.ConfigFile()
+		if err != nil {
+			return nil
+		}
+		if _, err := os.Stat(file); err != nil {
+			return nil
+		}
+		name = file
+	}
+
+	t, err := theme.Find(name)
+	if err != nil {
+		return err
+	}
+	currentTheme = t
+	return nil
+}
+
+// loadDotCommands reads the dot commands from the -dot flag's file, if given, and checks that
+// they are valid.
+func loadDotCommands() error {
+	if *dotFile == "" {
+		return nil
+	}
+	b, err := os.ReadFile(*dotFile)
+	if err != nil {
+		return err
+	}
+	if _, err := document.DefaultPageSetup().ReadDotCommands(strings.NewReader(string(b))); err != nil {
+		return fmt.Errorf("%v: %w", *dotFile, err)
+	}
+	dotCommands = string(b)
+	return nil
+}
+
+// loadMarks sets the formatting marks shown by ^OD from the -marks flag.
+func loadMarks() error {
+	o, err := layout.ParseViewOptions(*marks)
+	if err != nil {
+		return err
+	}
+	shownMarks = o
+	return nil
+}
+
+// drawRuler draws a ruler line showing the margins, indents and tab stops of the paragraph format
+// f. The margins are marked with 'L' and 'R', the indents of the lines with '[' and ']' and the
+// start of the first line with 'P'.
+func drawRuler(s surface.Surface, y int, f document.ParagraphFormat) {
+	rulerStyle := currentTheme.Style(theme.RoleRuler)
 	w, _ := s.Size()
-	pageWidth := int(math.Max(0, float64(w-m.LeftIndent-m.RightIndent)))
+	left, right := f.Margins(w)
+	start, end := f.LineColumns(w, false)
+	firstStart, _ := f.LineColumns(w, true)
 
 	for x := 0; x < w; x++ {
-		var c rune
-
-		px := x - m.LeftIndent
-		switch {
-		case px == 0:
-			c = '['
-		case px == pageWidth-1:
-			c = ']'
-		case x == 0:
-			c = 'L'
-		case x == w-1:
-			c = 'R'
-		case m.TabStop != 0 && px >= 0 && px < pageWidth && px%m.TabStop == 0:
-			c = '|'
-		default:
-			c = tcell.RuneBullet
+		c := runeBullet
+		if x > left && x < right {
+			c = '-'
 		}
-
 		s.SetContent(x, y, c, nil, rulerStyle)
 	}
-}
-*/
 
-func addText(s tcell.Screen, x, y int, text string, style tcell.Style) (newX int) {
-	newX = x
-	state := -1
-	var cluster string
+	for _, ts := range f.TabStopsBefore(right - 1) {
+		if ts.Column > left {
+			s.SetContent(ts.Column, y, tabStopRunes[ts.Kind], nil, rulerStyle)
+		}
+	}
 
-	for len(text) > 0 {
-		var width int
-		cluster, text, width, state = uniseg.FirstGraphemeClusterInString(text, state)
+	// Later markers take precedence so that the margins are always shown.
+	s.SetContent(start, y, '[', nil, rulerStyle)
+	s.SetContent(end-1, y, ']', nil, rulerStyle)
+	s.SetContent(firstStart, y, 'P', nil, rulerStyle)
+	s.SetContent(left, y, 'L', nil, rulerStyle)
+	s.SetContent(right-1, y, 'R', nil, rulerStyle)
+}
 
-		clusterRunes := []rune(cluster)
-		s.SetContent(newX, y, clusterRunes[0], clusterRunes[1:], style)
-		newX += width
+// itemStyle returns the style of a paragraph item in the current theme. Attributes of the item,
+// such as bold, are kept.
+func itemStyle(item layout.ParagraphItem) surface.Style {
+	role := theme.RoleText
+	if item.Markup {
+		role = theme.RoleMarkup
 	}
+	style := currentTheme.Style(role)
+	style.Attrs |= item.Style.Attrs
+	return style
+}
 
-	return newX
+// drawLine draws ln starting at column x of row y and returns the column after its last item.
+// Marks making spaces and tabs visible are drawn in the markup style.
+func drawLine(s surface.Surface, x, y int, ln layout.Line) int {
+	x += ln.Indent
+	for _, item := range ln.Items {
+		switch item.Type {
+		case layout.ParagraphItemTypeBox:
+			x = surface.DrawText(s, x, y, item.Text, itemStyle(item))
+		case layout.ParagraphItemTypeGlue, layout.ParagraphItemTypeTab:
+			end := x + item.Width
+			if item.Mark != "" && item.Width > 0 {
+				mark := item
+				mark.Markup = true
+				x = surface.DrawText(s, x, y, item.Mark, itemStyle(mark))
+			}
+			if end > x {
+				x = surface.DrawText(s, x, y, strings.Repeat(" ", end-x), itemStyle(item))
+			}
+		}
+	}
+	return x
 }
 
-func redraw(s tcell.Screen, l *layout.Layout, cp *document.Point) {
-	s.Clear()
+// textTop is the screen row of the first line of text. The ruler is drawn above it.
+const textTop = 1
+
+// redraw draws the ruler and the lines of l visible in the viewport v. The viewport is first
+// scrolled to follow the cursor at cp. Only the parts of the screen which differ from the frame
+// fr are drawn. If l shows page breaks, they are found by pb, which may be nil if there is none.
+func redraw(s surface.Surface, fr *frame, l *layout.Layout, pb *pageBreaker, v *layout.Viewport, cp *document.Point) {
+	fr.resize(s)
 	_, h := s.Size()
 
-	i := l.LineIterator(0)
-	for y := 0; y < h && !i.Done(); y++ {
-		_, ln := i.Next()
-		x := 0
-		for _, item := range ln {
-			switch item.Type {
-			case layout.ParagraphItemTypeBox:
-				x = addText(s, x, y, item.Text, item.Style)
-			case layout.ParagraphItemTypeGlue:
-				x = addText(s, x, y, " ", layout.StyleNormal)
-			}
+	var f document.ParagraphFormat
+	if cp != nil {
+		f = paragraphFormat(cp)
+	}
+	fr.drawRuler(s, 0, f)
+
+	v.Height = h - textTop
+	cx, cy, cursorErr := -1, -1, layout.ErrPointNotFound
+	if cp != nil {
+		cx, cy, cursorErr = l.CellLocationForPoint(cp)
+		if cursorErr == nil {
+			v.Follow(cy)
+		}
+	}
+
+	var pageEnds map[int]bool
+	if pb != nil && l.ViewOptions().PageBreaks {
+		pb.update(l.Document())
+		pageEnds = pageEndRows(l, pb.breaks(), v.Top, v.Height)
+	}
+
+	i := l.LineIterator(v.Top)
+	for y := textTop; y < h; y++ {
+		var ln layout.Line
+		lineIdx, done := -1, i.Done()
+		if !done {
+			lineIdx, ln = i.Next()
 		}
+		fr.drawRow(s, y-textTop, y, ln, done, !done && pageEnds[lineIdx])
 	}
 
 	s.HideCursor()
-	if cp != nil {
-		cx, cy, err := l.CellLocationForPoint(cp)
-		if err == nil {
-			s.ShowCursor(cx, cy)
+	if cursorErr == nil {
+		s.ShowCursor(cx, textTop+cy-v.Top)
+	}
+}
+
+// drawPreview draws the page of the print layout pl which contains the point cp as it will be
+// printed, including margins, header and footer.
+func drawPreview(s surface.Surface, pl *layout.Layout, cp *document.Point) {
+	surface.Fill(s, currentTheme.Style(theme.RoleText))
+	s.HideCursor()
+	w, h := s.Size()
+
+	pages := pl.Pages()
+	pageIdx, err := pl.PageIndexForPoint(cp)
+	if err != nil || pageIdx >= len(pages) {
+		pageIdx = 0
+	}
+	page := pages[pageIdx]
+	ps := pl.Document().PageSetup()
+
+	// Draw the page edge to the right of the text area.
+	pageEdgeStyle := currentTheme.Style(theme.RolePageBreak)
+	edgeX := ps.PageOffset + ps.Width
+	for y := 0; y < h && y < ps.Length; y++ {
+		s.SetContent(edgeX, y, runeVLine, nil, pageEdgeStyle)
+	}
+	if ps.Length < h {
+		for x := 0; x < edgeX && x < w; x++ {
+			s.SetContent(x, ps.Length, runeHLine, nil, pageEdgeStyle)
 		}
+		s.SetContent(edgeX, ps.Length, runeLRCorner, nil, pageEdgeStyle)
+	}
+
+	if y := ps.HeaderLine(); y >= 0 {
+		surface.DrawText(s, ps.PageOffset, y, page.Header, currentTheme.Style(theme.RoleText))
+	}
+	for i, ln := range page.Lines {
+		drawLine(s, ps.PageOffset, ps.TopMargin+i, ln)
+	}
+	if y := ps.FooterLine(); y >= 0 {
+		surface.DrawText(s, ps.PageOffset, y, page.Footer, currentTheme.Style(theme.RoleText))
 	}
 }
 
-func main() {
-	s, err := tcell.NewScreen()
+// commandKey returns the upper-case letter for a key which completes a prefixed command so that,
+// for example, ^O P, ^O p and ^O ^P are all treated alike. Returns 0 for other keys.
+func commandKey(ev *tcell.EventKey) rune {
+	switch {
+	case ev.Key() == tcell.KeyRune:
+		return unicode.ToUpper(ev.Rune())
+	case ev.Key() >= tcell.KeyCtrlA && ev.Key() <= tcell.KeyCtrlZ:
+		return rune('A' + ev.Key() - tcell.KeyCtrlA)
+	}
+	return 0
+}
+
+// paragraphFormat returns the format of the paragraph containing p.
+func paragraphFormat(p *document.Point) document.ParagraphFormat {
+	if para := p.Paragraph(); para != nil {
+		return para.Format()
+	}
+	return document.ParagraphFormat{}
+}
+
+// toggleAlignment sets the alignment of the paragraph containing p. If the paragraph already has
+// that alignment, it is set back to left aligned.
+func toggleAlignment(p *document.Point, align document.Alignment) *document.Point {
+	f := paragraphFormat(p)
+	if f.Alignment == align {
+		f.Alignment = document.AlignLeft
+	} else {
+		f.Alignment = align
+	}
+	return p.SetParagraphFormat(f)
+}
+
+// moveLines returns the point n lines of text below p, or above p if n is negative, nearest to
+// screen column goalX. Blank lines are skipped and movement stops at the first or last line.
+func moveLines(l *layout.Layout, p *document.Point, n, goalX int) *document.Point {
+	_, y, err := l.CellLocationForPoint(p)
 	if err != nil {
-		log.Fatalf("%+v", err)
+		return p
 	}
-	if err := s.Init(); err != nil {
-		log.Fatalf("%+v", err)
+
+	step := 1
+	if n < 0 {
+		step, n = -1, -n
+	}
+	for target := y + step; n > 0 && target >= 0; target += step {
+		i := l.LineIterator(target)
+		if i.Done() {
+			break
+		}
+		if _, ln := i.Next(); !ln.IsBlank() {
+			y = target
+			n--
+		}
 	}
 
-	// Set default text style
-	s.SetStyle(layout.StyleNormal)
+	return l.PointForCellLocation(goalX, y)
+}
 
-	// Clear screen
-	s.Clear()
+// movePage returns the point n screen rows below p, or above p if n is negative, nearest to screen
+// column goalX and scrolls the viewport v by the same number of rows so that the cursor keeps its
+// place on the screen. Blank rows are counted, so a page is always a screenful whatever the line
+// spacing. Movement stops at the first or last line.
+func movePage(l *layout.Layout, v *layout.Viewport, p *document.Point, n, goalX int) *document.Point {
+	_, y, err := l.CellLocationForPoint(p)
+	if err != nil {
+		return p
+	}
 
-	d := document.NewDocument()
-	w, _ := s.Size()
-	l, err := layout.NewLayout(d, w)
+	lineCount := l.LineCount()
+	target := y + n
+	if target > lineCount-1 {
+		target = lineCount - 1
+	}
+	if target < 0 {
+		target = 0
+	}
+
+	v.Scroll(target-y, lineCount)
+	return l.PointForCellLocation(goalX, target)
+}
+
+// textRowNear returns the row of text nearest to v.Clamp(y) on which the cursor may be placed
+// without the viewport v scrolling. Rows are only ever blank because of line or paragraph spacing,
+// and the cursor cannot be placed on them. If no row in the viewport has text, v.Clamp(y) is
+// returned.
+func textRowNear(l *layout.Layout, v layout.Viewport, y int) int {
+	y = v.Clamp(y)
+	lo, hi := v.Clamp(math.MinInt), v.Clamp(math.MaxInt)
+	isText := func(row int) bool {
+		if row < lo || row > hi {
+			return false
+		}
+		i := l.LineIterator(row)
+		if i.Done() {
+			return false
+		}
+		_, ln := i.Next()
+		return !ln.IsBlank()
+	}
+
+	for d := 0; y-d >= lo || y+d <= hi; d++ {
+		if isText(y - d) {
+			return y - d
+		}
+		if isText(y + d) {
+			return y + d
+		}
+	}
+	return y
+}
+
+// cycleLineSpacing changes the line spacing of the paragraph containing p from single to one and a
+// half, to double and back to single.
+func cycleLineSpacing(p *document.Point) *document.Point {
+	f := paragraphFormat(p)
+	f.LineSpacing = (f.LineSpacing + 1) % (document.LineSpacingDouble + 1)
+	return p.SetParagraphFormat(f)
+}
+
+// toggleViewOptions switches the layout between showing the formatting marks chosen with the
+// -marks flag and the marks it showed before, as WordStar's ^O D does. If those are the same, as
+// when the chosen marks are the default ones, no marks are shown instead.
+func toggleViewOptions(l *layout.Layout) {
+	if o := l.ViewOptions(); o != shownMarks {
+		otherMarks = o
+		l.SetViewOptions(shownMarks)
+		return
+	}
+	if otherMarks == shownMarks {
+		otherMarks = layout.ViewOptions{}
+	}
+	l.SetViewOptions(otherMarks)
+}
+
+// setRulerFromCursor applies a ruler command to the paragraph containing p using the screen column
+// of the cursor. Command 'L' sets the left margin, 'R' sets the right margin so that the cursor
+// column is the last one used, 'I' sets a tab stop and 'N' clears one.
+func setRulerFromCursor(l *layout.Layout, p *document.Point, command rune) *document.Point {
+	x, _, err := l.CellLocationForPoint(p)
 	if err != nil {
-		log.Fatalf("%+v", err)
+		return p
+	}
+
+	f := paragraphFormat(p)
+	switch command {
+	case 'L':
+		f.LeftMargin = x
+	case 'R':
+		f.RightMargin = x + 1
+	case 'I':
+		f = f.WithTabStop(document.TabStop{Column: x})
+	case 'N':
+		f = f.WithoutTabStop(x)
 	}
+	return p.SetParagraphFormat(f)
+}
+
+func exampleDocument() *document.Document {
+	d := document.NewDocument()
+
+	ps := d.PageSetup()
+	ps.Header = document.RunningText{Odd: "Example document", Even: "rwstar"}
+	ps.Footer = document.RunningText{Odd: "Page #", Even: "Page #"}
+	d = d.SetPageSetup(ps)
 
-	d = (d.
+	return (d.
 		StartPoint().
 		InsertText("This is an example paragraph.").End().
 		InsertText(" This is sentence two of an example paragraph. ").End().
@@ -121,10 +448,235 @@ func main() {
 		InsertParagraphBreak().End().
 		InsertText("And another example paragraph.").
 		Document())
-	l.SetDocument(d)
+}
+
+// newDocument returns the document to edit with settings from the command line applied.
+func newDocument() *document.Document {
+	d := exampleDocument()
+	if dotCommands != "" {
+		// The commands were checked when they were loaded.
+		ps, _ := d.PageSetup().ReadDotCommands(strings.NewReader(dotCommands))
+		d = d.SetPageSetup(ps)
+	}
+	if *language != "" {
+		d = d.SetHyphenation(document.HyphenationSettings{Language: *language})
+	}
+	return d
+}
+
+// configureLayout applies settings from the command line to a new layout.
+func configureLayout(l *layout.Layout, err error) (*layout.Layout, error) {
+	if err != nil {
+		return nil, err
+	}
+	if hyphenRegistry != nil {
+		l.SetHyphenationRegistry(hyphenRegistry)
+	}
+	return l, nil
+}
+
+// createOutput opens the named file for writing. The name "-" refers to standard output.
+func createOutput(name string) (io.WriteCloser, error) {
+	if name == "-" {
+		return nopWriteCloser{os.Stdout}, nil
+	}
+	return os.Create(name)
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+// printCommand implements "rwstar print" which writes the document as paginated plain text.
+func printCommand(args []string) error {
+	fs := flag.NewFlagSet("print", flag.ExitOnError)
+	outName := fs.String("o", "-", "write output to `file` (\"-\" for standard output)")
+	fs.Parse(args)
+
+	pl, err := configureLayout(layout.NewPrintLayout(newDocument()))
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(*outName)
+	if err != nil {
+		return err
+	}
+	if err := pl.WritePlainText(out); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// pdfCommand implements "rwstar pdf" which writes the document as a PDF.
+func pdfCommand(args []string) error {
+	fs := flag.NewFlagSet("pdf", flag.ExitOnError)
+	outName := fs.String("o", "-", "write output to `file` (\"-\" for standard output)")
+	fontName := fs.String("font", "courier", "font `family` to use: courier, times or helvetica")
+	afmDir := fs.String("afm", "", "read font metrics from AFM files in `dir` instead of the built-in metrics")
+	fs.Parse(args)
+
+	var opts pdf.Options
+	switch *fontName {
+	case "courier":
+		opts.Family = pdf.FamilyCourier
+	case "times":
+		opts.Family = pdf.FamilyTimes
+	case "helvetica":
+		opts.Family = pdf.FamilyHelvetica
+	default:
+		return fmt.Errorf("unknown font family: %v", *fontName)
+	}
+
+	m, err := pdf.LoadMeasurer(opts.Family, opts.FontSize, *afmDir)
+	if err != nil {
+		return err
+	}
+	pl, err := configureLayout(pdf.NewLayout(newDocument(), m))
+	if err != nil {
+		return err
+	}
+
+	out, err := createOutput(*outName)
+	if err != nil {
+		return err
+	}
+	if err := pdf.Write(out, pl, opts); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func main() {
+	flag.Parse()
+
+	if *patternsDir != "" {
+		hyphenRegistry = hyphen.NewRegistry(*patternsDir)
+		if *language != "" {
+			if _, err := hyphenRegistry.Get(*language); err != nil {
+				log.Fatalf("%+v", err)
+			}
+		}
+	}
+
+	if err := loadTheme(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if err := loadDotCommands(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if err := loadMarks(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	if args := flag.Args(); len(args) > 0 {
+		var err error
+		switch args[0] {
+		case "print":
+			err = printCommand(args[1:])
+		case "pdf":
+			err = pdfCommand(args[1:])
+		default:
+			log.Fatalf("unknown command: %v", args[0])
+		}
+		if err != nil {
+			log.Fatalf("%+v", err)
+		}
+		return
+	}
+
+	s, err := tcell.NewScreen()
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	if err := s.Init(); err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	s.EnableMouse()
+
+	// Set default text style
+	currentTheme = currentTheme.ForColors(s.Colors())
+	s.SetStyle(tcellsurface.Style(currentTheme.Style(theme.RoleText)))
+
+	// Clear screen
+	s.Clear()
+
+	d := newDocument()
+	w, _ := s.Size()
+	l, err := configureLayout(layout.NewLayout(d, w))
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	// Redraw once background layout has replaced estimated line counts with exact ones.
+	l.SetBackgroundIdleFunc(func() {
+		s.PostEvent(tcell.NewEventInterrupt(nil))
+	})
+
+	// The print layout wraps text at the page width rather than the screen width.
+	pl, err := configureLayout(layout.NewPrintLayout(d))
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+
+	// Page breaks are shown using a print layout of their own which is paginated in the
+	// background.
+	breakLayout, err := configureLayout(layout.NewPrintLayout(d))
+	if err != nil {
+		log.Fatalf("%+v", err)
+	}
+	pb := newPageBreaker(breakLayout, func() {
+		s.PostEvent(tcell.NewEventInterrupt(nil))
+	})
 
 	p := d.StartPoint().ForwardN(20)
-	redraw(s, l, p)
+	v := &layout.Viewport{ScrollMargin: *scrollMargin}
+	fr := &frame{}
+	ts := tcellsurface.New(s)
+	redraw(ts, fr, l, pb, v, p)
+
+	// prefix is the pending WordStar-style command prefix (^K, ^O, ^P or ^Q) or 0.
+	var prefix tcell.Key
+	preview := false
+
+	// goalX is the screen column which vertical motion tries to keep to. It is set by the first of
+	// a sequence of vertical motions and is -1 otherwise. keepGoal is set by each vertical motion.
+	goalX := -1
+	keepGoal := false
+	needRedraw := false
+	useGoal := func() int {
+		if goalX < 0 {
+			goalX, _, _ = l.CellLocationForPoint(p)
+		}
+		keepGoal = true
+		return goalX
+	}
+	moveVertically := func(n int) {
+		p = moveLines(l, p, n, useGoal())
+	}
+	pageVertically := func(n int) {
+		p = movePage(l, v, p, n, useGoal())
+		needRedraw = true
+	}
+	// moveToRow moves the cursor to a line of the layout in the goal column.
+	moveToRow := func(y int) {
+		p = l.PointForCellLocation(useGoal(), y)
+	}
+	// scroll scrolls the viewport by n lines, moving the cursor only if it would leave the screen.
+	scroll := func(n int) {
+		v.Scroll(n, l.LineCount())
+		if _, cy, err := l.CellLocationForPoint(p); err == nil && v.Clamp(cy) != cy {
+			moveToRow(textRowNear(l, *v, cy))
+		}
+		needRedraw = true
+	}
 
 	quit := func() {
 		s.Fini()
@@ -132,7 +684,8 @@ func main() {
 	}
 	for {
 		prevP := p
-		needRedraw := false
+		keepGoal = false
+		needRedraw = false
 
 		// Update screen
 		s.Show()
@@ -142,16 +695,88 @@ func main() {
 
 		// Process event
 		switch ev := ev.(type) {
+		case *tcell.EventInterrupt:
+			needRedraw = true
 		case *tcell.EventResize:
 			s.Sync()
+			w, _ = s.Size()
 			l.SetScreenWidth(w)
 			needRedraw = true
+		case *tcell.EventMouse:
+			if x, y := ev.Position(); ev.Buttons()&tcell.Button1 != 0 && !preview && y >= textTop {
+				p = l.PointForCellLocation(x, y-textTop+v.Top)
+			}
 		case *tcell.EventKey:
+			if prefix != 0 {
+				switch prefix {
+				case tcell.KeyCtrlO:
+					switch commandKey(ev) {
+					case 'P':
+						preview = !preview
+						needRedraw = true
+					case 'J':
+						p = toggleAlignment(p, document.AlignJustify)
+					case 'C':
+						p = toggleAlignment(p, document.AlignCentre)
+					case ']':
+						p = toggleAlignment(p, document.AlignRight)
+					case 'S':
+						p = cycleLineSpacing(p)
+					case 'D':
+						toggleViewOptions(l)
+						needRedraw = true
+					case 'L', 'R', 'I', 'N':
+						p = setRulerFromCursor(l, p, commandKey(ev))
+					}
+				case tcell.KeyCtrlK:
+					switch commandKey(ev) {
+					case 'Q':
+						quit()
+					}
+				case tcell.KeyCtrlQ:
+					switch commandKey(ev) {
+					case 'E':
+						moveToRow(textRowNear(l, *v, v.Top))
+					case 'X':
+						moveToRow(textRowNear(l, *v, v.Top+v.Height-1))
+					}
+				case tcell.KeyCtrlP:
+					switch commandKey(ev) {
+					case 'O':
+						// Insert a hard (no-break) space.
+						p = p.InsertText("\u00a0").End()
+					case '-':
+						// Insert a soft (discretionary) hyphen.
+						p = p.InsertText("\u00ad").End()
+					}
+				}
+				prefix = 0
+				break
+			}
+
 			switch ev.Key() {
-			case tcell.KeyEscape, tcell.KeyCtrlC:
+			case tcell.KeyEscape:
 				quit()
+			case tcell.KeyCtrlE, tcell.KeyUp:
+				moveVertically(-1)
+			case tcell.KeyCtrlX, tcell.KeyDown:
+				moveVertically(1)
+			case tcell.KeyCtrlR, tcell.KeyPgUp:
+				pageVertically(-v.Height)
+			case tcell.KeyCtrlC, tcell.KeyPgDn:
+				pageVertically(v.Height)
+			case tcell.KeyCtrlW:
+				scroll(-1)
+			case tcell.KeyCtrlZ:
+				scroll(1)
+			case tcell.KeyCtrlK, tcell.KeyCtrlO, tcell.KeyCtrlP, tcell.KeyCtrlQ:
+				// A prefix does not end a sequence of vertical motions.
+				prefix = ev.Key()
+				keepGoal = goalX >= 0
 			case tcell.KeyEnter:
 				p = p.InsertParagraphBreak().End()
+			case tcell.KeyTab:
+				p = p.InsertText("\t").End()
 			case tcell.KeyRight:
 				p = p.Forward()
 			case tcell.KeyRune:
@@ -159,10 +784,21 @@ func main() {
 			}
 		}
 
+		// Anything other than vertical motion forgets the goal column.
+		if !keepGoal {
+			goalX = -1
+		}
+
 		if needRedraw || p != prevP {
 			d = p.Document()
 			l.SetDocument(d)
-			redraw(s, l, p)
+			if preview {
+				pl.SetDocument(d)
+				drawPreview(ts, pl, p)
+				fr.invalidate()
+			} else {
+				redraw(ts, fr, l, pb, v, p)
+			}
 		}
 	}
 }
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/layout"
+)
+
+// doubleSpacedDocument returns a document of n double spaced paragraphs, "p0", "p1" and so on.
+func doubleSpacedDocument(n int) *document.Document {
+	p := document.NewDocument().StartPoint()
+	for i := 0; i < n; i++ {
+		if i > 0 {
+			p = p.InsertParagraphBreak().End()
+		}
+		p = p.SetParagraphFormat(document.ParagraphFormat{LineSpacing: document.LineSpacingDouble})
+		p = p.InsertText(fmt.Sprintf("p%d", i)).End()
+	}
+	return p.Document()
+}
+
+func TestMovePageCountsScreenRows(t *testing.T) {
+	d := doubleSpacedDocument(10)
+
+	l, err := layout.NewLayout(d, 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetBackgroundWorkers(0)
+
+	// Each double spaced paragraph takes two rows, so a page of four rows moves by two paragraphs.
+	v := &layout.Viewport{Height: 4}
+	p := movePage(l, v, d.StartPoint(), v.Height, 0)
+	if _, y, err := l.CellLocationForPoint(p); err != nil || y != 4 || v.Top != 4 {
+		t.Errorf("Page down: row %v (%v), viewport top %v", y, err, v.Top)
+	}
+	if para := p.Paragraph().String(); para != "p2" {
+		t.Errorf("Page down: paragraph %q", para)
+	}
+
+	p = movePage(l, v, p, -v.Height, 0)
+	if _, y, _ := l.CellLocationForPoint(p); y != 0 || v.Top != 0 {
+		t.Errorf("Page up: row %v, viewport top %v", y, v.Top)
+	}
+}
+
+func TestTextRowNearSkipsBlankRows(t *testing.T) {
+	l, err := layout.NewLayout(doubleSpacedDocument(10), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetBackgroundWorkers(0)
+
+	// Rows 3 to 6 may hold the cursor. Row 3 is a blank spacing row and row 2 would scroll.
+	v := layout.Viewport{Top: 2, Height: 6, ScrollMargin: 1}
+	if y := textRowNear(l, v, 0); y != 4 {
+		t.Errorf("Row near top: %v", y)
+	}
+	if y := textRowNear(l, v, 20); y != 6 {
+		t.Errorf("Row near bottom: %v", y)
+	}
+}
+
+func TestPageEndRowsFollowPrintLayout(t *testing.T) {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("one two three four five six").Document()
+	ps := d.PageSetup()
+	ps.Width = 9
+	ps.Length = 2
+	ps.TopMargin = 0
+	ps.BottomMargin = 0
+	d = d.SetPageSetup(ps)
+
+	pl, err := layout.NewPrintLayout(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l, err := layout.NewLayout(d, 12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetBackgroundWorkers(0)
+
+	done := make(chan struct{})
+	pb := newPageBreaker(pl, func() { close(done) })
+	pb.update(d)
+	<-done
+
+	// The screen lines are "one two", "three four" and "five six". The second page starts with
+	// "four", so the first screen line is the last before the break.
+	rows := pageEndRows(l, pb.breaks(), 0, 3)
+	if len(rows) != 1 || !rows[0] {
+		t.Errorf("Page end rows: %v", rows)
+	}
+
+	// Only breaks in the paragraphs shown are located.
+	if rows := pageEndRows(l, []pagePosition{{para: 1, offset: 0}}, 0, 3); len(rows) != 0 {
+		t.Errorf("Page end rows for a later paragraph: %v", rows)
+	}
+}
+
+func TestToggleViewOptions(t *testing.T) {
+	defer func(shown, other layout.ViewOptions) {
+		shownMarks, otherMarks = shown, other
+	}(shownMarks, otherMarks)
+
+	l, err := layout.NewLayout(document.NewDocument(), 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	l.SetBackgroundWorkers(0)
+
+	for _, shown := range []layout.ViewOptions{layout.AllViewOptions(), layout.DefaultViewOptions()} {
+		shownMarks, otherMarks = shown, layout.DefaultViewOptions()
+		l.SetViewOptions(layout.DefaultViewOptions())
+
+		var seen []layout.ViewOptions
+		for i := 0; i < 3; i++ {
+			toggleViewOptions(l)
+			seen = append(seen, l.ViewOptions())
+		}
+		if seen[0] == seen[1] || seen[0] != seen[2] {
+			t.Errorf("^OD with marks %+v shows %+v", shown, seen)
+		}
+		if seen[0] != shown && seen[1] != shown {
+			t.Errorf("^OD never shows marks %+v", shown)
+		}
+	}
+}
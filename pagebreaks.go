@@ -0,0 +1,114 @@
+package main
+
+import (
+	"math"
+	"sort"
+	"sync"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/layout"
+)
+
+// pagePosition is the paragraph index and text offset at which a printed page starts. Unlike a
+// point, it can be applied to later versions of the document.
+type pagePosition struct {
+	para, offset int
+}
+
+// pageBreaker finds where the printed pages of a document break on a background goroutine, so that
+// showing page breaks on the screen neither paginates the whole document on every redraw nor lays
+// out off-screen text on the UI goroutine.
+type pageBreaker struct {
+	// pl is the print layout which is paginated. It is only used by the background goroutine.
+	pl *layout.Layout
+
+	// notify is called from the background goroutine when new breaks have been found.
+	notify func()
+
+	mu sync.Mutex
+
+	// want is the document whose breaks were last asked for. running is true while the
+	// background goroutine is finding breaks.
+	want    *document.Document
+	running bool
+
+	// positions are the starts of the pages after the first in the document last paginated.
+	positions []pagePosition
+}
+
+func newPageBreaker(pl *layout.Layout, notify func()) *pageBreaker {
+	return &pageBreaker{pl: pl, notify: notify}
+}
+
+// update asks for the page breaks of d to be found unless they already have been or are being
+// found.
+func (pb *pageBreaker) update(d *document.Document) {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	if d == pb.want {
+		return
+	}
+	pb.want = d
+	if !pb.running {
+		pb.running = true
+		go pb.run()
+	}
+}
+
+// run paginates the wanted document until the breaks of the latest one are known.
+func (pb *pageBreaker) run() {
+	for {
+		pb.mu.Lock()
+		d := pb.want
+		pb.mu.Unlock()
+
+		pb.pl.SetDocument(d)
+		points := pb.pl.PageBreaks()
+		positions := make([]pagePosition, len(points))
+		for i, p := range points {
+			positions[i] = pagePosition{para: p.ParagraphIndex(), offset: p.TextOffset()}
+		}
+
+		pb.mu.Lock()
+		pb.positions = positions
+		done := d == pb.want
+		pb.running = !done
+		pb.mu.Unlock()
+
+		if done {
+			pb.notify()
+			return
+		}
+	}
+}
+
+// breaks returns the starts of the pages after the first in document order. They may be those of
+// an earlier version of the document if the latest has not yet been paginated.
+func (pb *pageBreaker) breaks() []pagePosition {
+	pb.mu.Lock()
+	defer pb.mu.Unlock()
+	return pb.positions
+}
+
+// pageEndRows returns the lines of l among the height lines from top which are followed by one of
+// the page breaks. Lines are wrapped differently on the screen and so the line before the one
+// containing the start of each page is used. Only the breaks in paragraphs on those lines are
+// located.
+func pageEndRows(l *layout.Layout, breaks []pagePosition, top, height int) map[int]bool {
+	d := l.Document()
+	firstPara := l.PointForCellLocation(-1, top).ParagraphIndex()
+	lastPara := l.PointForCellLocation(math.MaxInt, top+height).ParagraphIndex()
+
+	rows := make(map[int]bool)
+	i := sort.Search(len(breaks), func(i int) bool { return breaks[i].para >= firstPara })
+	for ; i < len(breaks) && breaks[i].para <= lastPara && breaks[i].para < d.ParagraphCount(); i++ {
+		offset := breaks[i].offset
+		if n := d.GetParagraph(breaks[i].para).TextLength(); offset > n {
+			offset = n
+		}
+		if _, y, err := l.CellLocationForPoint(d.PointAt(breaks[i].para, offset)); err == nil && y > 0 {
+			rows[y-1] = true
+		}
+	}
+	return rows
+}
@@ -0,0 +1,151 @@
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// FontMetrics holds the advance widths of the glyphs of a font in thousandths of the font size,
+// as given by an Adobe Font Metrics (AFM) file.
+type FontMetrics struct {
+	// FontName is the PostScript name of the font.
+	FontName string
+
+	// widths maps glyph names to widths.
+	widths map[string]int
+
+	// missingWidth is the width of characters without a glyph. They are printed as '?'.
+	missingWidth int
+}
+
+// courierMetrics are the metrics of every face of Courier, in which all glyphs have the same
+// width. They are built in since Courier is the default font.
+var courierMetrics = &FontMetrics{FontName: "Courier", missingWidth: 600}
+
+// ReadAFM reads font metrics in the Adobe Font Metrics file format from r. Only the font name and
+// the character widths are used.
+func ReadAFM(r io.Reader) (*FontMetrics, error) {
+	m := &FontMetrics{widths: make(map[string]int)}
+	inMetrics, sawMetrics := false, false
+
+	s := bufio.NewScanner(r)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+		key, value, _ := strings.Cut(line, " ")
+		switch {
+		case key == "FontName":
+			m.FontName = strings.TrimSpace(value)
+		case key == "StartCharMetrics":
+			inMetrics, sawMetrics = true, true
+		case key == "EndCharMetrics":
+			inMetrics = false
+		case inMetrics && line != "":
+			name, width, err := parseCharMetrics(line)
+			if err != nil {
+				return nil, err
+			}
+			if name != "" {
+				m.widths[name] = width
+			}
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if !sawMetrics {
+		return nil, fmt.Errorf("pdf: no character metrics in AFM file for %q", m.FontName)
+	}
+
+	m.missingWidth = m.widths["question"]
+	return m, nil
+}
+
+// parseCharMetrics parses a line of character metrics such as "C 32 ; WX 250 ; N space ; B 0 0 0
+// 0 ;" and returns the glyph name and width.
+func parseCharMetrics(line string) (name string, width int, err error) {
+	for _, field := range strings.Split(line, ";") {
+		key, value, _ := strings.Cut(strings.TrimSpace(field), " ")
+		value = strings.TrimSpace(value)
+		switch key {
+		case "WX", "W0X":
+			if width, err = strconv.Atoi(value); err != nil {
+				return "", 0, fmt.Errorf("pdf: bad width in AFM character metrics %q", line)
+			}
+		case "N":
+			name = value
+		}
+	}
+	return name, width, nil
+}
+
+// LoadAFM reads font metrics from the named AFM file.
+func LoadAFM(name string) (*FontMetrics, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadAFM(f)
+}
+
+// RuneWidth returns the width of r in thousandths of the font size.
+func (m *FontMetrics) RuneWidth(r rune) int {
+	name, ok := glyphNames[r]
+	if !ok {
+		name = fmt.Sprintf("uni%04X", r)
+	}
+	if w, ok := m.widths[name]; ok {
+		return w
+	}
+	return m.missingWidth
+}
+
+// TextWidth returns the width of text in thousandths of the font size.
+func (m *FontMetrics) TextWidth(text string) int {
+	w := 0
+	for _, r := range text {
+		w += m.RuneWidth(r)
+	}
+	return w
+}
+
+// glyphNames gives the standard glyph names of the characters in WinAnsiEncoding, which are those
+// the base fonts can print. No-break space and soft hyphen use the glyphs of their usual
+// counterparts.
+var glyphNames = func() map[rune]string {
+	ascii := strings.Fields(`space exclam quotedbl numbersign dollar percent ampersand quotesingle
+		parenleft parenright asterisk plus comma hyphen period slash zero one two three four five
+		six seven eight nine colon semicolon less equal greater question at A B C D E F G H I J K L
+		M N O P Q R S T U V W X Y Z bracketleft backslash bracketright asciicircum underscore grave
+		a b c d e f g h i j k l m n o p q r s t u v w x y z braceleft bar braceright asciitilde`)
+	latin1 := strings.Fields(`space exclamdown cent sterling currency yen brokenbar section
+		dieresis copyright ordfeminine guillemotleft logicalnot hyphen registered macron degree
+		plusminus twosuperior threesuperior acute mu paragraph periodcentered cedilla onesuperior
+		ordmasculine guillemotright onequarter onehalf threequarters questiondown Agrave Aacute
+		Acircumflex Atilde Adieresis Aring AE Ccedilla Egrave Eacute Ecircumflex Edieresis Igrave
+		Iacute Icircumflex Idieresis Eth Ntilde Ograve Oacute Ocircumflex Otilde Odieresis
+		multiply Oslash Ugrave Uacute Ucircumflex Udieresis Yacute Thorn germandbls agrave aacute
+		acircumflex atilde adieresis aring ae ccedilla egrave eacute ecircumflex edieresis igrave
+		iacute icircumflex idieresis eth ntilde ograve oacute ocircumflex otilde odieresis divide
+		oslash ugrave uacute ucircumflex udieresis yacute thorn ydieresis`)
+
+	names := map[rune]string{
+		'€': "Euro", '‚': "quotesinglbase", 'ƒ': "florin", '„': "quotedblbase", '…': "ellipsis",
+		'†': "dagger", '‡': "daggerdbl", 'ˆ': "circumflex", '‰': "perthousand", 'Š': "Scaron",
+		'‹': "guilsinglleft", 'Œ': "OE", 'Ž': "Zcaron", '‘': "quoteleft", '’': "quoteright",
+		'“': "quotedblleft", '”': "quotedblright", '•': "bullet", '–': "endash", '—': "emdash",
+		'˜': "tilde", '™': "trademark", 'š': "scaron", '›': "guilsinglright", 'œ': "oe",
+		'ž': "zcaron", 'Ÿ': "Ydieresis",
+	}
+	for i, name := range ascii {
+		names[rune(0x20+i)] = name
+	}
+	for i, name := range latin1 {
+		names[rune(0xa0+i)] = name
+	}
+	return names
+}()
@@ -0,0 +1,96 @@
+package pdf
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/surface"
+)
+
+func TestReadAFM(t *testing.T) {
+	m, err := LoadAFM("testdata/Times-Roman.afm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.FontName != "Times-Roman" {
+		t.Errorf("FontName is %q", m.FontName)
+	}
+	for r, expected := range map[rune]int{'H': 722, ' ': 250, ' ': 250, 'Z': 444} {
+		if w := m.RuneWidth(r); w != expected {
+			t.Errorf("Width of %q is %v, expected %v", r, w, expected)
+		}
+	}
+
+	if _, err := ReadAFM(strings.NewReader("StartFontMetrics 4.1\nEndFontMetrics\n")); err == nil {
+		t.Error("No error for AFM without metrics")
+	}
+}
+
+func TestGlyphNames(t *testing.T) {
+	for r, expected := range map[rune]string{'~': "asciitilde", 'ÿ': "ydieresis", '£': "sterling"} {
+		if name := glyphNames[r]; name != expected {
+			t.Errorf("Glyph name of %q is %q, expected %q", r, name, expected)
+		}
+	}
+}
+
+func TestMeasurer(t *testing.T) {
+	m, err := LoadMeasurer(FamilyTimes, 10, "testdata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := m.TextWidth("Hello", surface.StyleDefault); w != 22220 {
+		t.Errorf("Width of Hello is %v", w)
+	}
+	if w := m.ColumnWidth(); w != 6000 {
+		t.Errorf("Column width is %v", w)
+	}
+
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("Hello Hello").Document()
+	l, err := NewLayout(d, m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, l, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	if !strings.Contains(out, "/F0 10 Tf\n1 0 0 1 48.00 ") || !strings.Contains(out, "(Hello Hello) Tj") {
+		t.Error("Body text not set in 10 point Times at the page offset")
+	}
+}
+
+func TestBuiltinMetrics(t *testing.T) {
+	// The built-in metrics agree with the AFM files.
+	for _, name := range baseFontNames[FamilyTimes] {
+		afm, err := LoadAFM("testdata/" + name + ".afm")
+		if err != nil {
+			t.Fatal(err)
+		}
+		m, ok := builtinMetrics(name)
+		if !ok {
+			t.Fatalf("No built-in metrics for %v", name)
+		}
+		for glyph, w := range afm.widths {
+			if m.widths[glyph] != w {
+				t.Errorf("%v: width of %v is %v, expected %v", name, glyph, m.widths[glyph], w)
+			}
+		}
+	}
+
+	m, err := LoadMeasurer(FamilyHelvetica, 10, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := m.TextWidth("Hello€", surface.StyleDefault); w != 28340 {
+		t.Errorf("Width of Hello€ is %v", w)
+	}
+	if w := m.TextWidth("世", surface.Style{Attrs: surface.AttrBold}); w != 6110 {
+		t.Errorf("Width of a missing character is %v", w)
+	}
+}
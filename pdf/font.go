@@ -0,0 +1,81 @@
+package pdf
+
+import "strings"
+
+// Family is a family of the standard PDF base fonts.
+type Family int
+
+const (
+	// FamilyCourier is monospaced and so reproduces the screen layout exactly.
+	FamilyCourier Family = iota
+
+	// FamilyTimes is proportionally spaced.
+	FamilyTimes
+
+	// FamilyHelvetica is proportionally spaced and sans serif.
+	FamilyHelvetica
+)
+
+// variant indexes the four faces of a font family.
+type variant int
+
+const (
+	variantRegular variant = iota
+	variantBold
+	variantItalic
+	variantBoldItalic
+	variantCount
+)
+
+var baseFontNames = map[Family][variantCount]string{
+	FamilyCourier: {"Courier", "Courier-Bold", "Courier-Oblique", "Courier-BoldOblique"},
+	FamilyTimes:   {"Times-Roman", "Times-Bold", "Times-Italic", "Times-BoldItalic"},
+	FamilyHelvetica: {
+		"Helvetica", "Helvetica-Bold", "Helvetica-Oblique", "Helvetica-BoldOblique",
+	},
+}
+
+func variantFor(bold, italic bool) variant {
+	switch {
+	case bold && italic:
+		return variantBoldItalic
+	case bold:
+		return variantBold
+	case italic:
+		return variantItalic
+	}
+	return variantRegular
+}
+
+// winAnsiSpecials maps characters outside of Latin-1 onto their WinAnsiEncoding codes.
+var winAnsiSpecials = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8a, '‹': 0x8b, 'Œ': 0x8c, 'Ž': 0x8e, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9a, '›': 0x9b,
+	'œ': 0x9c, 'ž': 0x9e, 'Ÿ': 0x9f,
+}
+
+// encodeString converts text to a PDF literal string in WinAnsiEncoding. Characters which cannot
+// be encoded are replaced by '?'.
+func encodeString(text string) string {
+	var sb strings.Builder
+	sb.WriteByte('(')
+	for _, r := range text {
+		var c byte
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			sb.WriteByte('\\')
+			c = byte(r)
+		case r >= 0x20 && r < 0x7f, r >= 0xa0 && r <= 0xff:
+			c = byte(r)
+		default:
+			var ok bool
+			if c, ok = winAnsiSpecials[r]; !ok {
+				c = '?'
+			}
+		}
+		sb.WriteByte(c)
+	}
+	sb.WriteByte(')')
+	return sb.String()
+}
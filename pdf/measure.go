@@ -0,0 +1,81 @@
+package pdf
+
+import (
+	"fmt"
+	"math"
+	"path/filepath"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/layout"
+	"github.com/rjw57/rwstar/surface"
+)
+
+// Measurer is a layout.Measurer which measures text set in a family of base fonts using their
+// metrics. Widths are in thousandths of a point. A column is as wide as a Courier character, so
+// that margins, indents and tab stops are where they would be with the default font.
+type Measurer struct {
+	family   Family
+	fontSize float64
+	faces    [variantCount]*FontMetrics
+}
+
+// LoadMeasurer returns a Measurer for family set at fontSize points, or the default size if
+// fontSize is not positive. If dir is empty, the built-in metrics of the base fonts are used.
+// Otherwise the metrics of each face are read from dir, which holds the AFM files of the base
+// fonts named after the fonts, for example "Times-Roman.afm". The metrics of Courier are always
+// built in.
+func LoadMeasurer(family Family, fontSize float64, dir string) (*Measurer, error) {
+	names, ok := baseFontNames[family]
+	if !ok {
+		return nil, errUnknownFamily(family)
+	}
+	if fontSize <= 0 {
+		fontSize = defaultFontSize
+	}
+
+	m := &Measurer{family: family, fontSize: fontSize}
+	for v, name := range names {
+		var err error
+		switch {
+		case family == FamilyCourier:
+			m.faces[v] = courierMetrics
+		case dir == "":
+			var ok bool
+			if m.faces[v], ok = builtinMetrics(name); !ok {
+				err = fmt.Errorf("pdf: no built-in metrics for %q", name)
+			}
+		default:
+			m.faces[v], err = LoadAFM(filepath.Join(dir, name+".afm"))
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	return m, nil
+}
+
+func (m *Measurer) TextWidth(text string, style surface.Style) int {
+	v := variantFor(style.Attrs&surface.AttrBold != 0, style.Attrs&surface.AttrItalic != 0)
+	return m.scale(m.faces[v].TextWidth(text))
+}
+
+func (m *Measurer) ColumnWidth() int {
+	return m.scale(courierMetrics.missingWidth)
+}
+
+// scale converts a width in thousandths of the font size to thousandths of a point.
+func (m *Measurer) scale(w int) int {
+	return int(math.Round(float64(w) * m.fontSize))
+}
+
+// NewLayout returns a layout of d for printing with text measured by m. Its screen width is the
+// width of the page setup's text.
+func NewLayout(d *document.Document, m *Measurer) (*layout.Layout, error) {
+	l, err := layout.NewPrintLayout(d)
+	if err != nil {
+		return nil, err
+	}
+	l.SetMeasurer(m)
+	l.SetScreenWidth(d.PageSetup().Width * m.ColumnWidth())
+	return l, nil
+}
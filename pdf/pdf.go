@@ -0,0 +1,185 @@
+// Package pdf writes laid out documents as PDF files using only the standard PDF base fonts.
+package pdf
+
+import (
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/rjw57/rwstar/layout"
+	"github.com/rjw57/rwstar/surface"
+)
+
+const (
+	letterWidth     = 612
+	letterHeight    = 792
+	defaultFontSize = 12
+
+	// courierAdvance is the width of every Courier glyph as a fraction of the font size.
+	courierAdvance = 0.6
+)
+
+// Options controls how the PDF is written. The zero value gives US Letter pages set in 12 point
+// Courier which, with the default page setup, matches a 10 character per inch line printer.
+type Options struct {
+	// Family is the font family used for all text.
+	Family Family
+
+	// FontSize is the size of the font in points.
+	FontSize float64
+
+	// PageWidth and PageHeight give the size of the page in points.
+	PageWidth  float64
+	PageHeight float64
+}
+
+func (o Options) withDefaults() Options {
+	if o.FontSize <= 0 {
+		o.FontSize = defaultFontSize
+	}
+	if o.PageWidth <= 0 || o.PageHeight <= 0 {
+		o.PageWidth, o.PageHeight = letterWidth, letterHeight
+	}
+	return o
+}
+
+// run is a horizontal run of text in a single font starting at a given position in the units of
+// the layout.
+type run struct {
+	column  int
+	variant variant
+	text    strings.Builder
+}
+
+// lineRuns converts a laid out line into runs of text. Markup is omitted. Bold and italic styles
+// select the corresponding font variant. Tabs and glue which has been stretched end the current
+// run so that the following text starts at its exact position.
+func lineRuns(ln layout.Line, column int) []*run {
+	var runs []*run
+	var current *run
+
+	column += ln.Indent
+	for _, item := range ln.Items {
+		switch {
+		case item.Markup:
+			// Markup is never printed.
+		case item.Type == layout.ParagraphItemTypeBox:
+			attrs := item.Style.Attrs
+			v := variantFor(attrs&surface.AttrBold != 0, attrs&surface.AttrItalic != 0)
+			if current == nil || current.variant != v {
+				current = &run{column: column, variant: v}
+				runs = append(runs, current)
+			}
+			current.text.WriteString(item.Text)
+		case item.Type == layout.ParagraphItemTypeGlue:
+			if current != nil && item.Width == item.NaturalWidth {
+				current.text.WriteString(item.Text)
+			} else {
+				current = nil
+			}
+		case item.Type == layout.ParagraphItemTypeTab:
+			current = nil
+		}
+		column += item.Width
+	}
+
+	return runs
+}
+
+// pageContent returns the content stream for a single page. Positions within the layout are
+// converted to points by multiplying by unit. A column is colWidth units wide.
+func pageContent(l *layout.Layout, page layout.Page, opts Options, unit float64, colWidth int) string {
+	ps := l.Document().PageSetup()
+	lineHeight := opts.PageHeight / float64(ps.Length)
+	offset := ps.PageOffset * colWidth
+
+	var sb strings.Builder
+	sb.WriteString("BT\n")
+	addRuns := func(row int, runs []*run) {
+		y := opts.PageHeight - float64(row+1)*lineHeight + 0.25*lineHeight
+		for _, r := range runs {
+			fmt.Fprintf(&sb, "/F%d %g Tf\n", r.variant, opts.FontSize)
+			fmt.Fprintf(&sb, "1 0 0 1 %.2f %.2f Tm\n", float64(r.column)*unit, y)
+			fmt.Fprintf(&sb, "%s Tj\n", encodeString(r.text.String()))
+		}
+	}
+	textRuns := func(column int, text string) []*run {
+		r := &run{column: column}
+		r.text.WriteString(text)
+		return []*run{r}
+	}
+
+	if row := ps.HeaderLine(); row >= 0 && page.Header != "" {
+		addRuns(row, textRuns(offset, page.Header))
+	}
+	for i, ln := range page.Lines {
+		addRuns(ps.TopMargin+i, lineRuns(ln, offset))
+	}
+	if row := ps.FooterLine(); row >= 0 && page.Footer != "" {
+		addRuns(row, textRuns(offset, page.Footer))
+	}
+
+	sb.WriteString("ET")
+	return sb.String()
+}
+
+// errUnknownFamily returns the error for an unknown font family.
+func errUnknownFamily(family Family) error {
+	return fmt.Errorf("pdf: unknown font family %v", family)
+}
+
+// Write paginates the layout according to its document's page setup and writes it to w as a PDF.
+// The layout's screen width should normally match the page setup. See layout.NewPrintLayout.
+//
+// If the layout measures text with a Measurer, as made by NewLayout, the font family and size are
+// those of the Measurer. Otherwise text is placed on a grid of Courier character widths whatever
+// the family.
+func Write(w io.Writer, l *layout.Layout, opts Options) error {
+	opts = opts.withDefaults()
+	unit, colWidth := courierAdvance*opts.FontSize, 1
+	if m, ok := l.Measurer().(*Measurer); ok {
+		opts.Family, opts.FontSize = m.family, m.fontSize
+		unit, colWidth = 0.001, m.ColumnWidth()
+	}
+
+	names, ok := baseFontNames[opts.Family]
+	if !ok {
+		return errUnknownFamily(opts.Family)
+	}
+
+	ow := newObjectWriter(w)
+	catalog := ow.reserve()
+	pagesObj := ow.reserve()
+	resources := ow.reserve()
+
+	var fontRefs strings.Builder
+	for v, name := range names {
+		num := ow.reserve()
+		ow.object(num, fmt.Sprintf(
+			"<< /Type /Font /Subtype /Type1 /BaseFont /%s /Encoding /WinAnsiEncoding >>", name,
+		))
+		fmt.Fprintf(&fontRefs, " /F%d %d 0 R", v, num)
+	}
+	ow.object(resources, fmt.Sprintf("<< /Font <<%s >> >>", fontRefs.String()))
+
+	var kids strings.Builder
+	pages := l.Pages()
+	for _, page := range pages {
+		pageObj := ow.reserve()
+		contentObj := ow.reserve()
+		ow.stream(contentObj, pageContent(l, page, opts, unit, colWidth))
+		ow.object(pageObj, fmt.Sprintf(
+			"<< /Type /Page /Parent %d 0 R /Resources %d 0 R /Contents %d 0 R >>",
+			pagesObj, resources, contentObj,
+		))
+		fmt.Fprintf(&kids, " %d 0 R", pageObj)
+	}
+
+	ow.object(pagesObj, fmt.Sprintf(
+		"<< /Type /Pages /Kids [%s ] /Count %d /MediaBox [0 0 %g %g] >>",
+		kids.String(), len(pages), opts.PageWidth, opts.PageHeight,
+	))
+	ow.object(catalog, fmt.Sprintf("<< /Type /Catalog /Pages %d 0 R >>", pagesObj))
+
+	return ow.finish(catalog)
+}
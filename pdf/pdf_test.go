@@ -0,0 +1,88 @@
+package pdf
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/rjw57/rwstar/document"
+	"github.com/rjw57/rwstar/layout"
+)
+
+func writeTestPDF(t *testing.T, opts Options) string {
+	d := document.NewDocument()
+	d = d.StartPoint().InsertText("Hello (world) £5").Document()
+	ps := d.PageSetup()
+	ps.Footer = document.NewRunningText("Page #")
+	d = d.SetPageSetup(ps)
+
+	l, err := layout.NewPrintLayout(d)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, l, opts); err != nil {
+		t.Fatal(err)
+	}
+	return buf.String()
+}
+
+func TestWriteStructure(t *testing.T) {
+	out := writeTestPDF(t, Options{})
+
+	if !strings.HasPrefix(out, "%PDF-1.4\n") {
+		t.Error("Missing PDF header")
+	}
+	if !strings.HasSuffix(out, "%%EOF\n") {
+		t.Error("Missing EOF marker")
+	}
+
+	// Every cross-reference entry must point at the start of its object.
+	m := regexp.MustCompile(`startxref\n(\d+)\n`).FindStringSubmatch(out)
+	if m == nil {
+		t.Fatal("Missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(m[1])
+	if !strings.HasPrefix(out[xrefOffset:], "xref\n") {
+		t.Fatal("startxref does not point at xref table")
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllStringSubmatch(out[xrefOffset:], -1)
+	for i, e := range entries {
+		offset, _ := strconv.Atoi(e[1])
+		if prefix := fmt.Sprintf("%d 0 obj\n", i+1); !strings.HasPrefix(out[offset:], prefix) {
+			t.Errorf("Object %d not at offset %d", i+1, offset)
+		}
+	}
+
+	for _, s := range []string{"/BaseFont /Courier ", "/BaseFont /Courier-Bold ", "/Count 1 "} {
+		if !strings.Contains(out, s) {
+			t.Errorf("Output does not contain %#v", s)
+		}
+	}
+	if !strings.Contains(out, "(Hello \\(world\\) \xa35) Tj") {
+		t.Error("Body text not found")
+	}
+	if !strings.Contains(out, "(Page 1) Tj") {
+		t.Error("Footer not found")
+	}
+}
+
+func TestWriteTimes(t *testing.T) {
+	out := writeTestPDF(t, Options{Family: FamilyTimes})
+	if !strings.Contains(out, "/BaseFont /Times-Roman ") {
+		t.Error("Times not used")
+	}
+	if strings.Contains(out, "/BaseFont /Courier") {
+		t.Error("Courier used")
+	}
+}
+
+func TestEncodeString(t *testing.T) {
+	if s := encodeString("a—b€世"); s != "(a\x97b\x80?)" {
+		t.Errorf("Encoded: %#v", s)
+	}
+}
@@ -0,0 +1,65 @@
+package pdf
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+)
+
+// objectWriter writes numbered PDF objects and records their offsets for the cross-reference
+// table.
+type objectWriter struct {
+	w       *bufio.Writer
+	offset  int
+	offsets []int
+	err     error
+}
+
+func newObjectWriter(w io.Writer) *objectWriter {
+	ow := &objectWriter{w: bufio.NewWriter(w)}
+	// The binary comment marks the file as containing 8-bit data.
+	ow.printf("%%PDF-1.4\n%%\xe2\xe3\xcf\xd3\n")
+	return ow
+}
+
+func (ow *objectWriter) printf(format string, args ...any) {
+	if ow.err != nil {
+		return
+	}
+	n, err := fmt.Fprintf(ow.w, format, args...)
+	ow.offset += n
+	ow.err = err
+}
+
+// reserve allocates an object number to be written later.
+func (ow *objectWriter) reserve() int {
+	ow.offsets = append(ow.offsets, -1)
+	return len(ow.offsets)
+}
+
+// object writes the body of a previously reserved object.
+func (ow *objectWriter) object(num int, body string) {
+	ow.offsets[num-1] = ow.offset
+	ow.printf("%d 0 obj\n%s\nendobj\n", num, body)
+}
+
+// stream writes a previously reserved object as a stream with the passed content.
+func (ow *objectWriter) stream(num int, content string) {
+	ow.object(num, fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content))
+}
+
+// finish writes the cross-reference table and trailer and flushes the output.
+func (ow *objectWriter) finish(root int) error {
+	xrefOffset := ow.offset
+	ow.printf("xref\n0 %d\n0000000000 65535 f \n", len(ow.offsets)+1)
+	for _, o := range ow.offsets {
+		ow.printf("%010d 00000 n \n", o)
+	}
+	ow.printf("trailer\n<< /Size %d /Root %d 0 R >>\n", len(ow.offsets)+1, root)
+	ow.printf("startxref\n%d\n%%%%EOF\n", xrefOffset)
+
+	if ow.err != nil {
+		return ow.err
+	}
+	return ow.w.Flush()
+}
@@ -0,0 +1,102 @@
+package surface
+
+import (
+	"strings"
+
+	"github.com/rivo/uniseg"
+)
+
+// Cell is a single cell of a Grid.
+type Cell struct {
+	// Mainc is the main rune shown in the cell. It is 0 for the cell to the right of a wide
+	// character.
+	Mainc rune
+
+	// Combc holds any combining runes for the cell.
+	Combc []rune
+
+	// Style is the appearance of the cell.
+	Style Style
+}
+
+// Grid is a Surface held in memory. It is useful for testing what would be drawn on a display and
+// as the basis for other front ends.
+type Grid struct {
+	width, height int
+	cells         []Cell
+
+	cursorX, cursorY int
+	cursorVisible    bool
+}
+
+// NewGrid returns a grid of width by height cells. Every cell is a space in the default style.
+func NewGrid(width, height int) *Grid {
+	g := &Grid{width: width, height: height, cells: make([]Cell, width*height)}
+	for i := range g.cells {
+		g.cells[i].Mainc = ' '
+	}
+	return g
+}
+
+func (g *Grid) Size() (int, int) {
+	return g.width, g.height
+}
+
+func (g *Grid) SetContent(x, y int, mainc rune, combc []rune, style Style) {
+	if x < 0 || y < 0 || x >= g.width || y >= g.height {
+		return
+	}
+	g.cells[y*g.width+x] = Cell{Mainc: mainc, Combc: append([]rune(nil), combc...), Style: style}
+
+	// A wide character covers the following cell.
+	if x+1 < g.width && uniseg.StringWidth(string(mainc)+string(combc)) > 1 {
+		g.cells[y*g.width+x+1] = Cell{Style: style}
+	}
+}
+
+func (g *Grid) ShowCursor(x, y int) {
+	g.cursorX, g.cursorY, g.cursorVisible = x, y, true
+}
+
+func (g *Grid) HideCursor() {
+	g.cursorVisible = false
+}
+
+// Cell returns the cell at column x and row y. Cells outside the grid are returned as the zero
+// Cell.
+func (g *Grid) Cell(x, y int) Cell {
+	if x < 0 || y < 0 || x >= g.width || y >= g.height {
+		return Cell{}
+	}
+	return g.cells[y*g.width+x]
+}
+
+// Cursor returns the location of the cursor. Returns false if it is hidden.
+func (g *Grid) Cursor() (x, y int, visible bool) {
+	return g.cursorX, g.cursorY, g.cursorVisible
+}
+
+// Row returns the text of row y with trailing spaces removed. Styles are ignored.
+func (g *Grid) Row(y int) string {
+	var sb strings.Builder
+	for x := 0; x < g.width; x++ {
+		c := g.Cell(x, y)
+		if c.Mainc == 0 {
+			continue
+		}
+		sb.WriteRune(c.Mainc)
+		for _, r := range c.Combc {
+			sb.WriteRune(r)
+		}
+	}
+	return strings.TrimRight(sb.String(), " ")
+}
+
+// String returns the text of every row, separated by newlines.
+func (g *Grid) String() string {
+	rows := make([]string, g.height)
+	for y := range rows {
+		rows[y] = g.Row(y)
+	}
+	return strings.Join(rows, "\n")
+}
@@ -0,0 +1,37 @@
+package surface
+
+import "testing"
+
+func TestGridDrawText(t *testing.T) {
+	g := NewGrid(8, 2)
+	style := StyleDefault.Foreground(ColorRed).Bold(true)
+	if x := DrawText(g, 1, 0, "a世é", style); x != 5 {
+		t.Errorf("DrawText returned %v, expected 5", x)
+	}
+	DrawText(g, 6, 1, "overflow", StyleDefault)
+
+	if got, expected := g.String(), " a世e\u0301\n      ov"; got != expected {
+		t.Errorf("Got %q, expected %q", got, expected)
+	}
+	if c := g.Cell(3, 0); c.Mainc != 0 || c.Style != style {
+		t.Errorf("Cell covered by wide character is %+v", c)
+	}
+	if c := g.Cell(4, 0); c.Mainc != 'e' || len(c.Combc) != 1 || c.Style.Attrs != AttrBold {
+		t.Errorf("Cell with combining character is %+v", c)
+	}
+}
+
+func TestColor(t *testing.T) {
+	if n, ok := ColorNavy.Palette(); !ok || n != 4 {
+		t.Errorf("ColorNavy has palette index %v, %v", n, ok)
+	}
+	if _, _, _, ok := ColorNavy.RGB(); ok {
+		t.Error("ColorNavy is RGB")
+	}
+	if r, g, b, ok := RGBColor(1, 2, 3).RGB(); !ok || r != 1 || g != 2 || b != 3 {
+		t.Errorf("RGBColor(1, 2, 3) is %v, %v, %v, %v", r, g, b, ok)
+	}
+	if _, ok := ColorDefault.Palette(); ok {
+		t.Error("ColorDefault is a palette colour")
+	}
+}
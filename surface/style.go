@@ -0,0 +1,116 @@
+// Package surface describes how text is drawn independently of any particular display. A Surface
+// is a grid of character cells. The tcellsurface package draws on a terminal and Grid draws in
+// memory.
+package surface
+
+// Color is a colour. The zero value is the display's default colour. Other colours are either an
+// index into the display's palette or a 24-bit RGB value.
+type Color uint32
+
+const (
+	ColorDefault Color = 0
+
+	colorPalette Color = 1 << 24
+	colorRGB     Color = 1 << 25
+	colorValue   Color = 1<<24 - 1
+)
+
+// The first 16 palette colours, which are available on all colour terminals. Only the first 8 are
+// available on the most basic ones.
+const (
+	ColorBlack Color = colorPalette | iota
+	ColorMaroon
+	ColorGreen
+	ColorOlive
+	ColorNavy
+	ColorPurple
+	ColorTeal
+	ColorSilver
+	ColorGray
+	ColorRed
+	ColorLime
+	ColorYellow
+	ColorBlue
+	ColorFuchsia
+	ColorAqua
+	ColorWhite
+)
+
+// PaletteColor returns the colour with index n in the display's palette.
+func PaletteColor(n uint8) Color {
+	return colorPalette | Color(n)
+}
+
+// RGBColor returns the colour with the given red, green and blue components.
+func RGBColor(r, g, b uint8) Color {
+	return colorRGB | Color(r)<<16 | Color(g)<<8 | Color(b)
+}
+
+// Palette returns the palette index of c. Returns false if c is not a palette colour.
+func (c Color) Palette() (uint8, bool) {
+	return uint8(c & colorValue), c&colorPalette != 0
+}
+
+// RGB returns the components of c. Returns false if c is not an RGB colour.
+func (c Color) RGB() (r, g, b uint8, ok bool) {
+	return uint8(c >> 16), uint8(c >> 8), uint8(c), c&colorRGB != 0
+}
+
+// AttrMask is a set of text attributes.
+type AttrMask uint
+
+const (
+	AttrBold AttrMask = 1 << iota
+	AttrItalic
+	AttrUnderline
+	AttrReverse
+	AttrDim
+
+	AttrNone AttrMask = 0
+)
+
+// Style is the appearance of text. The zero value is the display's default appearance.
+type Style struct {
+	Fg, Bg Color
+	Attrs  AttrMask
+}
+
+// StyleDefault is the display's default appearance.
+var StyleDefault Style
+
+// Foreground returns a copy of the style with foreground colour c.
+func (s Style) Foreground(c Color) Style {
+	s.Fg = c
+	return s
+}
+
+// Background returns a copy of the style with background colour c.
+func (s Style) Background(c Color) Style {
+	s.Bg = c
+	return s
+}
+
+// Attributes returns a copy of the style with attributes attrs.
+func (s Style) Attributes(attrs AttrMask) Style {
+	s.Attrs = attrs
+	return s
+}
+
+// Bold returns a copy of the style with bold set or cleared.
+func (s Style) Bold(on bool) Style {
+	return s.setAttr(AttrBold, on)
+}
+
+// Italic returns a copy of the style with italic set or cleared.
+func (s Style) Italic(on bool) Style {
+	return s.setAttr(AttrItalic, on)
+}
+
+func (s Style) setAttr(attr AttrMask, on bool) Style {
+	if on {
+		s.Attrs |= attr
+	} else {
+		s.Attrs &^= attr
+	}
+	return s
+}
@@ -0,0 +1,53 @@
+package surface
+
+import "github.com/rivo/uniseg"
+
+// Surface is a grid of character cells which can be drawn on.
+type Surface interface {
+	// Size returns the number of columns and rows of cells.
+	Size() (width, height int)
+
+	// SetContent sets the cell at column x and row y. The cell shows the main rune mainc followed
+	// by the combining runes combc. Cells outside the surface are ignored. A wide character
+	// occupies the following cell as well.
+	SetContent(x, y int, mainc rune, combc []rune, style Style)
+
+	// ShowCursor shows the cursor at column x and row y.
+	ShowCursor(x, y int)
+
+	// HideCursor hides the cursor.
+	HideCursor()
+}
+
+// DrawText draws text starting at column x of row y and returns the column following it. Each
+// grapheme cluster occupies as many cells as its display width.
+func DrawText(s Surface, x, y int, text string, style Style) int {
+	state := -1
+	var cluster string
+
+	for len(text) > 0 {
+		var width int
+		cluster, text, width, state = uniseg.FirstGraphemeClusterInString(text, state)
+
+		clusterRunes := []rune(cluster)
+		s.SetContent(x, y, clusterRunes[0], clusterRunes[1:], style)
+		x += width
+	}
+
+	return x
+}
+
+// Fill sets every cell of the surface to a space drawn in style.
+func Fill(s Surface, style Style) {
+	w, h := s.Size()
+	for y := 0; y < h; y++ {
+		FillRow(s, 0, y, w, style)
+	}
+}
+
+// FillRow sets n cells of row y starting at column x to spaces drawn in style.
+func FillRow(s Surface, x, y, n int, style Style) {
+	for i := 0; i < n; i++ {
+		s.SetContent(x+i, y, ' ', nil, style)
+	}
+}
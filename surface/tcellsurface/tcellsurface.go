@@ -0,0 +1,67 @@
+// Package tcellsurface draws surfaces on a terminal using tcell.
+package tcellsurface
+
+import (
+	"github.com/gdamore/tcell/v2"
+	"github.com/rjw57/rwstar/surface"
+)
+
+// Surface is a surface.Surface which draws on a tcell screen.
+type Surface struct {
+	screen tcell.Screen
+}
+
+// New returns a surface which draws on s.
+func New(s tcell.Screen) *Surface {
+	return &Surface{screen: s}
+}
+
+func (s *Surface) Size() (int, int) {
+	return s.screen.Size()
+}
+
+func (s *Surface) SetContent(x, y int, mainc rune, combc []rune, style surface.Style) {
+	s.screen.SetContent(x, y, mainc, combc, Style(style))
+}
+
+func (s *Surface) ShowCursor(x, y int) {
+	s.screen.ShowCursor(x, y)
+}
+
+func (s *Surface) HideCursor() {
+	s.screen.HideCursor()
+}
+
+// attrs maps surface attributes to tcell attributes.
+var attrs = []struct {
+	from surface.AttrMask
+	to   tcell.AttrMask
+}{
+	{surface.AttrBold, tcell.AttrBold},
+	{surface.AttrItalic, tcell.AttrItalic},
+	{surface.AttrUnderline, tcell.AttrUnderline},
+	{surface.AttrReverse, tcell.AttrReverse},
+	{surface.AttrDim, tcell.AttrDim},
+}
+
+// Color returns the tcell colour for c.
+func Color(c surface.Color) tcell.Color {
+	if n, ok := c.Palette(); ok {
+		return tcell.PaletteColor(int(n))
+	}
+	if r, g, b, ok := c.RGB(); ok {
+		return tcell.NewRGBColor(int32(r), int32(g), int32(b))
+	}
+	return tcell.ColorDefault
+}
+
+// Style returns the tcell style for st.
+func Style(st surface.Style) tcell.Style {
+	var mask tcell.AttrMask
+	for _, a := range attrs {
+		if st.Attrs&a.from != 0 {
+			mask |= a.to
+		}
+	}
+	return tcell.StyleDefault.Foreground(Color(st.Fg)).Background(Color(st.Bg)).Attributes(mask)
+}
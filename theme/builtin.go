@@ -0,0 +1,101 @@
+package theme
+
+import "github.com/rjw57/rwstar/surface"
+
+// DefaultName is the name of the built-in theme used if no other is chosen.
+const DefaultName = "classic"
+
+// builtins are the themes which need no configuration file, in the order they are listed.
+var builtins = []*Theme{classic(), light(), highContrast()}
+
+// classic is white text on blue, as WordStar was usually configured.
+func classic() *Theme {
+	t := &Theme{Name: "classic"}
+	text := surface.StyleDefault.Foreground(surface.RGBColor(211, 211, 211)).Background(surface.RGBColor(0, 0, 139))
+	t.styles = [roleCount]surface.Style{
+		RoleText:        text,
+		RoleMarkup:      text.Foreground(surface.RGBColor(0, 139, 139)),
+		RoleSelection:   text.Foreground(surface.ColorNavy).Background(surface.ColorSilver),
+		RoleStatusBar:   surface.StyleDefault.Foreground(surface.ColorBlack).Background(surface.ColorTeal),
+		RoleRuler:       surface.StyleDefault,
+		RolePageBreak:   text.Foreground(surface.RGBColor(0, 139, 139)),
+		RoleSearchMatch: text.Foreground(surface.ColorBlack).Background(surface.ColorYellow),
+		RoleHeading1:    text.Foreground(surface.ColorWhite).Bold(true),
+		RoleHeading2:    text.Foreground(surface.ColorYellow).Bold(true),
+		RoleHeading3:    text.Foreground(surface.ColorAqua).Bold(true),
+		RoleHeading4:    text.Bold(true),
+		RoleHeading5:    text.Bold(true),
+		RoleHeading6:    text.Bold(true),
+	}
+	return t
+}
+
+// light is black text on white.
+func light() *Theme {
+	t := &Theme{Name: "light"}
+	text := surface.StyleDefault.Foreground(surface.ColorBlack).Background(surface.ColorWhite)
+	t.styles = [roleCount]surface.Style{
+		RoleText:        text,
+		RoleMarkup:      text.Foreground(surface.ColorGray),
+		RoleSelection:   text.Foreground(surface.ColorWhite).Background(surface.ColorBlue),
+		RoleStatusBar:   text.Foreground(surface.ColorWhite).Background(surface.ColorNavy),
+		RoleRuler:       text.Background(surface.ColorSilver),
+		RolePageBreak:   text.Foreground(surface.ColorGray),
+		RoleSearchMatch: text.Background(surface.ColorYellow),
+		RoleHeading1:    text.Foreground(surface.ColorNavy).Bold(true),
+		RoleHeading2:    text.Foreground(surface.ColorPurple).Bold(true),
+		RoleHeading3:    text.Foreground(surface.ColorTeal).Bold(true),
+		RoleHeading4:    text.Bold(true),
+		RoleHeading5:    text.Bold(true),
+		RoleHeading6:    text.Bold(true),
+	}
+	return t
+}
+
+// highContrast uses only black, white and the brightest colours.
+func highContrast() *Theme {
+	t := &Theme{Name: "high-contrast"}
+	text := surface.StyleDefault.Foreground(surface.ColorWhite).Background(surface.ColorBlack)
+	inverse := text.Foreground(surface.ColorBlack).Background(surface.ColorWhite)
+	t.styles = [roleCount]surface.Style{
+		RoleText:        text,
+		RoleMarkup:      text.Foreground(surface.ColorYellow),
+		RoleSelection:   text.Foreground(surface.ColorBlack).Background(surface.ColorAqua),
+		RoleStatusBar:   inverse.Bold(true),
+		RoleRuler:       inverse,
+		RolePageBreak:   text.Foreground(surface.ColorYellow),
+		RoleSearchMatch: text.Foreground(surface.ColorBlack).Background(surface.ColorLime),
+		RoleHeading1:    text.Attributes(surface.AttrBold | surface.AttrUnderline),
+		RoleHeading2:    text.Bold(true),
+		RoleHeading3:    text.Bold(true),
+		RoleHeading4:    text.Bold(true),
+		RoleHeading5:    text.Bold(true),
+		RoleHeading6:    text.Bold(true),
+	}
+	return t
+}
+
+// Builtin returns a copy of the built-in theme called name. Returns false if there is none.
+func Builtin(name string) (*Theme, bool) {
+	for _, t := range builtins {
+		if t.Name == name {
+			return t.clone(), true
+		}
+	}
+	return nil, false
+}
+
+// BuiltinNames returns the names of the built-in themes.
+func BuiltinNames() []string {
+	names := make([]string, len(builtins))
+	for i, t := range builtins {
+		names[i] = t.Name
+	}
+	return names
+}
+
+// Default returns a copy of the default theme.
+func Default() *Theme {
+	t, _ := Builtin(DefaultName)
+	return t
+}
@@ -0,0 +1,195 @@
+package theme
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rjw57/rwstar/surface"
+)
+
+// colorNames are the names of the first 16 palette colours in theme files.
+var colorNames = map[string]surface.Color{
+	"default": surface.ColorDefault,
+	"black":   surface.ColorBlack, "maroon": surface.ColorMaroon, "green": surface.ColorGreen,
+	"olive": surface.ColorOlive, "navy": surface.ColorNavy, "purple": surface.ColorPurple,
+	"teal": surface.ColorTeal, "silver": surface.ColorSilver, "gray": surface.ColorGray,
+	"red": surface.ColorRed, "lime": surface.ColorLime, "yellow": surface.ColorYellow,
+	"blue": surface.ColorBlue, "fuchsia": surface.ColorFuchsia, "aqua": surface.ColorAqua,
+	"white": surface.ColorWhite,
+}
+
+// attrNames are the names of text attributes in theme files.
+var attrNames = map[string]surface.AttrMask{
+	"bold": surface.AttrBold, "italic": surface.AttrItalic, "underline": surface.AttrUnderline,
+	"reverse": surface.AttrReverse, "dim": surface.AttrDim,
+}
+
+// Parse reads a theme from r. Each line of a theme file is blank, a comment starting with '#' or
+// a setting of the form "key = value". The keys are:
+//
+//	name   the name of the theme
+//	base   a built-in theme whose styles are used for roles the file does not set
+//	ROLE   the style of a role, such as text, markup or heading1
+//
+// A style is a foreground colour, optionally followed by "on" and a background colour, followed by
+// any of the attributes bold, italic, underline, reverse and dim. Colours are "default", one of
+// the 16 standard colour names such as navy or silver, "colorN" for palette index N or "#rrggbb".
+// For example:
+//
+//	base = classic
+//	text = silver on navy
+//	heading1 = white on navy bold underline
+//
+// Roles not set by the file or its base have the style of the text role.
+func Parse(r io.Reader) (*Theme, error) {
+	t := &Theme{}
+	var set [roleCount]bool
+
+	s := bufio.NewScanner(r)
+	for lineNum := 1; s.Scan(); lineNum++ {
+		line := strings.TrimSpace(s.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("theme: line %d: expected key = value", lineNum)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+
+		switch key {
+		case "name":
+			t.Name = value
+		case "base":
+			base, ok := Builtin(value)
+			if !ok {
+				return nil, fmt.Errorf("theme: line %d: unknown base theme %q", lineNum, value)
+			}
+			for r := Role(0); r < roleCount; r++ {
+				if !set[r] {
+					t.styles[r] = base.styles[r]
+					set[r] = true
+				}
+			}
+		default:
+			r, ok := roleNamed(key)
+			if !ok {
+				return nil, fmt.Errorf("theme: line %d: unknown role %q", lineNum, key)
+			}
+			style, err := parseStyle(value)
+			if err != nil {
+				return nil, fmt.Errorf("theme: line %d: %w", lineNum, err)
+			}
+			t.styles[r], set[r] = style, true
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+
+	for r := Role(0); r < roleCount; r++ {
+		if !set[r] {
+			t.styles[r] = t.styles[RoleText]
+		}
+	}
+	return t, nil
+}
+
+// Load reads the theme file called name. If the file does not name the theme, it is named after
+// the file.
+func Load(name string) (*Theme, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	t, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", name, err)
+	}
+	if t.Name == "" {
+		t.Name = strings.TrimSuffix(filepath.Base(name), filepath.Ext(name))
+	}
+	return t, nil
+}
+
+// Find returns the built-in theme called name or, if there is none, loads name as a theme file.
+func Find(name string) (*Theme, error) {
+	if t, ok := Builtin(name); ok {
+		return t, nil
+	}
+	return Load(name)
+}
+
+// ConfigFile returns the location of the user's theme file. It need not exist.
+func ConfigFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "rwstar", "theme.conf"), nil
+}
+
+func roleNamed(name string) (Role, bool) {
+	for r, n := range roleNames {
+		if n == name {
+			return Role(r), true
+		}
+	}
+	return 0, false
+}
+
+// parseStyle parses a style such as "white on navy bold".
+func parseStyle(value string) (surface.Style, error) {
+	var style surface.Style
+	fields := strings.Fields(strings.ToLower(value))
+	if len(fields) == 0 {
+		return style, fmt.Errorf("missing style")
+	}
+
+	var err error
+	if style.Fg, err = parseColor(fields[0]); err != nil {
+		return style, err
+	}
+	fields = fields[1:]
+	if len(fields) >= 2 && fields[0] == "on" {
+		if style.Bg, err = parseColor(fields[1]); err != nil {
+			return style, err
+		}
+		fields = fields[2:]
+	}
+
+	for _, f := range fields {
+		attr, ok := attrNames[f]
+		if !ok {
+			return style, fmt.Errorf("unknown attribute %q", f)
+		}
+		style.Attrs |= attr
+	}
+	return style, nil
+}
+
+// parseColor parses a colour name, "colorN" or "#rrggbb".
+func parseColor(name string) (surface.Color, error) {
+	if c, ok := colorNames[name]; ok {
+		return c, nil
+	}
+	if strings.HasPrefix(name, "color") {
+		if i, err := strconv.ParseUint(name[len("color"):], 10, 8); err == nil {
+			return surface.PaletteColor(uint8(i)), nil
+		}
+	}
+	if strings.HasPrefix(name, "#") && len(name) == 7 {
+		if v, err := strconv.ParseUint(name[1:], 16, 32); err == nil {
+			return surface.RGBColor(uint8(v>>16), uint8(v>>8), uint8(v)), nil
+		}
+	}
+	return surface.ColorDefault, fmt.Errorf("unknown colour %q", name)
+}
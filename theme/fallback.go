@@ -0,0 +1,100 @@
+package theme
+
+import "github.com/rjw57/rwstar/surface"
+
+// ForColors returns the theme reduced to use only the first n palette colours, as for a terminal
+// which supports n colours. Other colours are replaced by the nearest available one. If that would
+// make the text of a role invisible against its background, black or white text is used instead.
+// With fewer than 8 colours, all colours are dropped and roles which relied on a background colour
+// to stand out are shown reversed. Themes are returned unchanged for terminals with 256 or more
+// colours, which can approximate any colour themselves.
+func (t *Theme) ForColors(n int) *Theme {
+	if n >= 256 {
+		return t
+	}
+
+	r := t.clone()
+	text := t.styles[RoleText]
+	for role, style := range t.styles {
+		if n < 8 {
+			if style.Bg != text.Bg {
+				style.Attrs |= surface.AttrReverse
+			}
+			style.Fg, style.Bg = surface.ColorDefault, surface.ColorDefault
+			r.styles[role] = style
+			continue
+		}
+
+		style.Fg, style.Bg = nearestColor(style.Fg, n), nearestColor(style.Bg, n)
+		if style.Fg == style.Bg && style.Fg != surface.ColorDefault {
+			style.Fg = contrastingColor(style.Bg)
+		}
+		r.styles[role] = style
+	}
+	return r
+}
+
+// nearestColor returns the colour among the first n palette colours nearest to c.
+func nearestColor(c surface.Color, n int) surface.Color {
+	if c == surface.ColorDefault {
+		return c
+	}
+	if i, ok := c.Palette(); ok && int(i) < n {
+		return c
+	}
+
+	r, g, b := rgbOf(c)
+	best, bestDist := 0, -1
+	for i := 0; i < n; i++ {
+		pr, pg, pb := rgbOf(surface.PaletteColor(uint8(i)))
+		dr, dg, db := r-pr, g-pg, b-pb
+		if d := dr*dr + dg*dg + db*db; bestDist < 0 || d < bestDist {
+			best, bestDist = i, d
+		}
+	}
+	return surface.PaletteColor(uint8(best))
+}
+
+// contrastingColor returns black or white, whichever is more visible against c.
+func contrastingColor(c surface.Color) surface.Color {
+	r, g, b := rgbOf(c)
+	if 299*r+587*g+114*b > 128*1000 {
+		return surface.ColorBlack
+	}
+	return surface.ColorWhite
+}
+
+// ansiColors are the usual RGB values of the first 16 palette colours.
+var ansiColors = [16][3]int{
+	{0, 0, 0}, {128, 0, 0}, {0, 128, 0}, {128, 128, 0},
+	{0, 0, 128}, {128, 0, 128}, {0, 128, 128}, {192, 192, 192},
+	{128, 128, 128}, {255, 0, 0}, {0, 255, 0}, {255, 255, 0},
+	{0, 0, 255}, {255, 0, 255}, {0, 255, 255}, {255, 255, 255},
+}
+
+// rgbOf returns the RGB components of c. Palette colours beyond the first 16 follow the xterm
+// 256-colour palette.
+func rgbOf(c surface.Color) (r, g, b int) {
+	if r, g, b, ok := c.RGB(); ok {
+		return int(r), int(g), int(b)
+	}
+
+	i, _ := c.Palette()
+	switch {
+	case i < 16:
+		rgb := ansiColors[i]
+		return rgb[0], rgb[1], rgb[2]
+	case i < 232:
+		level := func(v uint8) int {
+			if v == 0 {
+				return 0
+			}
+			return 55 + 40*int(v)
+		}
+		i -= 16
+		return level(i / 36), level(i / 6 % 6), level(i % 6)
+	default:
+		v := 8 + 10*int(i-232)
+		return v, v, v
+	}
+}
@@ -0,0 +1,85 @@
+// Package theme maps the roles of things drawn by the editor, such as text, markup and the ruler,
+// onto styles. Themes may be built in or loaded from a configuration file and are reduced to fit
+// terminals with only 8 or 16 colours.
+package theme
+
+import (
+	"fmt"
+
+	"github.com/rjw57/rwstar/surface"
+)
+
+// Role is the part played by something drawn by the editor.
+type Role int
+
+const (
+	RoleText Role = iota
+	RoleMarkup
+	RoleSelection
+	RoleStatusBar
+	RoleRuler
+	RolePageBreak
+	RoleSearchMatch
+	RoleHeading1
+	RoleHeading2
+	RoleHeading3
+	RoleHeading4
+	RoleHeading5
+	RoleHeading6
+	roleCount
+)
+
+// HeadingLevels is the number of heading levels which have their own role.
+const HeadingLevels = int(RoleHeading6-RoleHeading1) + 1
+
+// roleNames are the names of roles in theme files.
+var roleNames = [roleCount]string{
+	"text", "markup", "selection", "statusbar", "ruler", "pagebreak", "searchmatch",
+	"heading1", "heading2", "heading3", "heading4", "heading5", "heading6",
+}
+
+func (r Role) String() string {
+	if r < 0 || r >= roleCount {
+		return fmt.Sprintf("Role(%d)", int(r))
+	}
+	return roleNames[r]
+}
+
+// HeadingRole returns the role of a heading at level, counting from one. Levels beyond the last
+// share its role.
+func HeadingRole(level int) Role {
+	switch {
+	case level < 1:
+		level = 1
+	case level > HeadingLevels:
+		level = HeadingLevels
+	}
+	return RoleHeading1 + Role(level-1)
+}
+
+// Theme gives the style of each role.
+type Theme struct {
+	// Name identifies the theme.
+	Name string
+
+	styles [roleCount]surface.Style
+}
+
+// Style returns the style of role r.
+func (t *Theme) Style(r Role) surface.Style {
+	if r < 0 || r >= roleCount {
+		return t.styles[RoleText]
+	}
+	return t.styles[r]
+}
+
+// SetStyle sets the style of role r.
+func (t *Theme) SetStyle(r Role, style surface.Style) {
+	t.styles[r] = style
+}
+
+// clone returns a copy of the theme.
+func (t *Theme) clone() *Theme {
+	c := *t
+	return &c
+}
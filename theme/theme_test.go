@@ -0,0 +1,96 @@
+package theme
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rjw57/rwstar/surface"
+)
+
+func TestParse(t *testing.T) {
+	th, err := Parse(strings.NewReader(`
+# A test theme.
+name = test
+text = silver on navy
+heading1 = #ffffff on color4 bold underline
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if th.Name != "test" {
+		t.Errorf("Name is %q", th.Name)
+	}
+
+	text := surface.StyleDefault.Foreground(surface.ColorSilver).Background(surface.ColorNavy)
+	for r, expected := range map[Role]surface.Style{
+		RoleText:     text,
+		RoleMarkup:   text,
+		RoleHeading1: text.Foreground(surface.RGBColor(255, 255, 255)).Attributes(surface.AttrBold | surface.AttrUnderline),
+	} {
+		if got := th.Style(r); got != expected {
+			t.Errorf("Style of %v is %+v, expected %+v", r, got, expected)
+		}
+	}
+
+	for _, bad := range []string{"text", "text = mauve", "text = red blinking", "colour = red", "base = none"} {
+		if _, err := Parse(strings.NewReader(bad)); err == nil {
+			t.Errorf("No error for %q", bad)
+		}
+	}
+}
+
+func TestParseBase(t *testing.T) {
+	th, err := Parse(strings.NewReader("markup = red\nbase = light\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	base, _ := Builtin("light")
+	if th.Style(RoleMarkup).Fg != surface.ColorRed {
+		t.Error("Base overrode earlier setting")
+	}
+	if th.Style(RoleRuler) != base.Style(RoleRuler) {
+		t.Error("Base not used for unset role")
+	}
+}
+
+func TestForColors(t *testing.T) {
+	classic, _ := Builtin("classic")
+	text := classic.ForColors(16).Style(RoleText)
+	if text.Fg != surface.ColorSilver || text.Bg != surface.ColorNavy {
+		t.Errorf("16 colour text is %+v", text)
+	}
+	if classic.ForColors(256) != classic {
+		t.Error("256 colour theme changed")
+	}
+
+	// White on silver would be silver on silver with 8 colours, so black text is used.
+	th := &Theme{}
+	th.SetStyle(RoleText, surface.StyleDefault.Foreground(surface.ColorWhite).Background(surface.ColorSilver))
+	if s := th.ForColors(8).Style(RoleText); s.Fg != surface.ColorBlack || s.Bg != surface.ColorSilver {
+		t.Errorf("Invisible text became %+v", s)
+	}
+
+	mono := classic.ForColors(2)
+	if s := mono.Style(RoleSelection); s.Fg != surface.ColorDefault || s.Attrs&surface.AttrReverse == 0 {
+		t.Errorf("Monochrome selection is %+v", s)
+	}
+	if s := mono.Style(RoleMarkup); s.Attrs&surface.AttrReverse != 0 {
+		t.Errorf("Monochrome markup is %+v", s)
+	}
+}
+
+func TestBuiltins(t *testing.T) {
+	for _, name := range BuiltinNames() {
+		th, err := Find(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		th.SetStyle(RoleText, surface.StyleDefault)
+		if orig, _ := Builtin(name); orig.Style(RoleText) == surface.StyleDefault {
+			t.Errorf("Changing a copy of %v changed the built-in theme", name)
+		}
+	}
+	if HeadingRole(9) != RoleHeading6 || HeadingRole(2) != RoleHeading2 {
+		t.Error("Wrong heading roles")
+	}
+}